@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// doctor checks the setup and prints what it finds. It returns the number of
+// warnings. The repositories checked are dirs, or when none are given the
+// working directories of the tasks in the cost database and the current
+// directory.
+func doctor(cfg *config.Config, dirs []string) int {
+	warnings := 0
+	ok := func(format string, args ...interface{}) {
+		fmt.Printf("ok      "+format+"\n", args...)
+	}
+	warn := func(format string, args ...interface{}) {
+		warnings++
+		fmt.Printf("warning "+format+"\n", args...)
+	}
+
+	if len(cfg.TasksRoots) == 0 {
+		warn("no tasks directory found in the %d usual locations, set tasks_roots", len(config.CandidateAgentTasksRoots()))
+	}
+	for _, root := range cfg.TasksRoots {
+		if _, err := os.Stat(root); err != nil {
+			warn("tasks directory %s: %v", root, err)
+		} else {
+			ok("tasks directory %s", root)
+		}
+	}
+
+	if cfg.Database != "" {
+		costStore, err := store.Open(cfg.Database)
+		if err != nil {
+			warn("cost database %s: %v", cfg.Database, err)
+		} else {
+			ok("cost database %s", cfg.Database)
+			if len(dirs) == 0 {
+				tasks, err := costStore.Tasks(time.Time{})
+				if err != nil {
+					warn("cost database %s: %v", cfg.Database, err)
+				}
+				for _, task := range tasks {
+					if task.WorkingDirectory != "" {
+						dirs = append(dirs, task.WorkingDirectory)
+					}
+				}
+			}
+			costStore.Close()
+		}
+	}
+	if len(dirs) == 0 {
+		if cwd, err := os.Getwd(); err == nil {
+			dirs = append(dirs, cwd)
+		}
+	}
+
+	// Tasks share the logs directory of their repository, so check each once
+	checked := map[string]bool{}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		repoConfig, err := uilogparser.LoadRepositoryConfig(dir)
+		if err != nil {
+			warn("%s: %v", dir, err)
+			continue
+		}
+		if repoConfig != nil && repoConfig.Disabled {
+			continue
+		}
+		opts, err := repoConfig.Apply(cfg.Options, dir)
+		if err != nil {
+			warn("%s: %v", dir, err)
+			continue
+		}
+		logsDir, err := opts.LogsDirectory(dir)
+		if err != nil {
+			warn("%s: %v", dir, err)
+			continue
+		}
+		if checked[logsDir] {
+			continue
+		}
+		checked[logsDir] = true
+
+		check, err := uilogparser.CheckLogsInGit(logsDir)
+		if err != nil {
+			warn("%s: %v", logsDir, err)
+			continue
+		}
+		if check == nil {
+			continue
+		}
+		switch {
+		case len(check.Tracked) > 0:
+			warn("%s: %d files under %s are tracked by git, remove them with: git -C %s rm -r --cached %s",
+				check.Root, len(check.Tracked), check.Directory, check.Root, check.Directory)
+		case len(check.History) > 0:
+			warn("%s: %d files under %s are in %d commits of git history, which may hold transcript text; rewrite the history to remove them",
+				check.Root, len(check.History), check.Directory, check.Commits)
+		default:
+			ok("%s: no files under %s in git", check.Root, check.Directory)
+		}
+		if !check.Ignored {
+			warn("%s: %s is not ignored by git, set output.git_ignore to %s or %s", check.Root, check.Directory, uilogparser.GitIgnoreExclude, uilogparser.GitIgnoreFile)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// importClaudeCode records every Claude Code session transcript under
+// projectsDir in the cost database, alongside the Cline tasks already there.
+// Sessions imported before are replaced, so it is safe to run repeatedly.
+func importClaudeCode(cfg *config.Config, projectsDir string) error {
+	if cfg.Database == "" {
+		return fmt.Errorf("importing needs the cost database, set -db")
+	}
+	if projectsDir == "" {
+		projectsDir = uilogparser.ClaudeCodeProjectsDir()
+	}
+	transcripts, err := uilogparser.FindClaudeCodeTranscripts(projectsDir)
+	if err != nil {
+		return err
+	}
+	if len(transcripts) == 0 {
+		return fmt.Errorf("no Claude Code sessions found in %s", projectsDir)
+	}
+
+	costStore, err := store.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer costStore.Close()
+
+	opts := cfg.Options
+	opts.Source = uilogparser.SourceClaudeCode
+	var imported int
+	var totalCost float64
+	for _, transcript := range transcripts {
+		ledger, err := uilogparser.LoadLedger(transcript, opts)
+		if errors.Is(err, uilogparser.ErrTrackingDisabled) {
+			log.Printf("Skipping %s: %v", transcript, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", transcript, err)
+			continue
+		}
+		if err := costStore.UpsertLedger(ledger); err != nil {
+			return err
+		}
+		imported++
+		totalCost += ledger.Task.TotalCost
+	}
+
+	log.Printf("Imported %d of %d Claude Code sessions from %s, costing $%.4f, into %s", imported, len(transcripts), projectsDir, totalCost, cfg.Database)
+	return nil
+}
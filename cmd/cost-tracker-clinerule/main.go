@@ -1,103 +1,84 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
-	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/pkg/ui-log-parser"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
 )
 
-// extractWorkingDirectoryFromTask extracts the working directory from ui_messages.json
-func extractWorkingDirectoryFromTask(taskFilePath string) string {
-	log.Printf("DEBUG: Extracting working directory from: %s", taskFilePath)
-
-	file, err := os.Open(taskFilePath)
+func main() {
+	// Settings come from the config file, then the environment, then flags
+	cfg, err := config.Load(config.PathFromArgs(os.Args[1:]))
 	if err != nil {
-		log.Printf("DEBUG: Error opening task file: %v", err)
-		return ""
+		log.Fatalf("Error loading config: %v", err)
 	}
-	defer file.Close()
-
-	// Read the file content
-	content := make([]byte, 10240) // Read first 10KB to find the working directory
-	n, err := file.Read(content)
-	if err != nil && n == 0 {
-		log.Printf("DEBUG: Error reading task file: %v", err)
-		return ""
+	cfg.RegisterFlags(flag.CommandLine)
+	importClaudeCodeFlag := flag.Bool("import-claude-code", false, "record every Claude Code session under the given projects directory, ~/.claude/projects by default, in the cost database")
+	doctorFlag := flag.Bool("doctor", false, "check the setup, and warn when the logs of the given repositories, or of every repository in the cost database, are tracked or not ignored by git")
+	flag.Parse()
+
+	if *doctorFlag {
+		if warnings := doctor(cfg, flag.Args()); warnings > 0 {
+			fmt.Printf("%d warnings\n", warnings)
+			os.Exit(1)
+		}
+		return
 	}
 
-	contentStr := string(content[:n])
-	log.Printf("DEBUG: Read %d bytes from task file", n)
-
-	// Look for the pattern "# Current Working Directory (/path/to/directory)"
-	workingDirPattern := "# Current Working Directory ("
-	startIdx := strings.Index(contentStr, workingDirPattern)
-	if startIdx == -1 {
-		log.Printf("DEBUG: Working directory pattern not found")
-		return ""
+	if *importClaudeCodeFlag {
+		if err := importClaudeCode(cfg, flag.Arg(0)); err != nil {
+			log.Fatalf("Error importing Claude Code sessions: %v", err)
+		}
+		return
 	}
 
-	// Find the start of the path
-	pathStart := startIdx + len(workingDirPattern)
-
-	// Find the end of the path (closing parenthesis)
-	pathEnd := strings.Index(contentStr[pathStart:], ")")
-	if pathEnd == -1 {
-		log.Printf("DEBUG: Could not find end of working directory path")
-		return ""
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: go run main.go [-config path] [-format csv|jsonl|bundle|anonymized] [-profile name] [-timezone zone] [-currency code -fx-rates path] [-redact] [-db path] [-source cline|roo-code|kilo-code|claude-code] <path_to_ui_messages.json or session.jsonl>\n       go run main.go -import-claude-code [-db path] [projects_dir]\n       go run main.go -doctor [repository...]")
 	}
 
-	workingDir := contentStr[pathStart : pathStart+pathEnd]
-	log.Printf("DEBUG: Extracted working directory: %s", workingDir)
-	return workingDir
-}
-
-// detectRepositoryRoot attempts to find the repository root where Cline is working
-func detectRepositoryRoot(taskFilePath string) (string, error) {
-	log.Printf("DEBUG: Starting repository detection for task file: %s", taskFilePath)
+	inputPath := flag.Arg(0)
 
-	// Extract working directory from the ui_messages.json file
-	if workingDir := extractWorkingDirectoryFromTask(taskFilePath); workingDir != "" {
-		log.Printf("DEBUG: Extracted working directory from task: %s", workingDir)
-		log.Printf("DEBUG: Using extracted working directory directly as repository root: %s", workingDir)
-		return workingDir, nil
+	// Write the logs to the repository of the task's working directory
+	result, err := uilogparser.ProcessUILogAutoAt(inputPath, cfg.Options)
+	if errors.Is(err, uilogparser.ErrTrackingDisabled) {
+		log.Printf("Skipping %s: %v", inputPath, err)
+		return
 	}
-
-	log.Printf("DEBUG: Could not extract working directory from task context")
-	return "", fmt.Errorf("could not extract working directory from task context")
-}
-
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run main.go <path_to_ui_messages.json>")
+	if err != nil {
+		log.Fatalf("Error processing UI log: %v", err)
 	}
 
-	inputPath := os.Args[1]
+	for _, signal := range result.Report.OngoingLoops() {
+		log.Printf("WARNING: Task %s looks stuck in a loop: %s", result.Report.TaskID, uilogparser.FormatLoopSignal(signal))
+	}
 
-	// Try to detect the repository root where Cline is working
-	repoRoot, err := detectRepositoryRoot(inputPath)
-	if err != nil {
-		log.Printf("Warning: Could not detect repository root (%v), falling back to current directory", err)
-		// Fallback to current working directory
-		repoRoot, err = os.Getwd()
-		if err != nil {
-			log.Fatalf("Error getting current working directory: %v", err)
+	// Record the task in the cost database shared with the MCP servers
+	var costStore *store.Store
+	if cfg.Database != "" {
+		if costStore, err = store.Open(cfg.Database); err != nil {
+			log.Printf("Warning: cost database unavailable: %v", err)
+		} else {
+			defer costStore.Close()
+			if err := costStore.UpsertLedger(result.Ledger); err != nil {
+				log.Printf("Warning: failed to store task in cost database: %v", err)
+			}
 		}
 	}
 
-	// Create the target log directory: {repoRoot}/ui-log-parser
-	logBasePath := filepath.Join(repoRoot, "ui-log-parser")
-
-	log.Printf("DEBUG: Detected repository root: %s", repoRoot)
-	log.Printf("DEBUG: Using ProcessUILogToCSVAutoAt with basePath: %s", logBasePath)
-
-	// Use the new function that creates logs directory in the detected repository
-	if err := uilogparser.ProcessUILogToCSVAutoAt(inputPath, logBasePath); err != nil {
-		log.Fatalf("Error processing UI log: %v", err)
+	warnings, err := cfg.Budgets.ForRepository(result.RepoConfig).Check(result.Ledger, costStore)
+	if err != nil {
+		log.Printf("Warning: failed to check budgets: %v", err)
+	}
+	for _, warning := range warnings {
+		log.Printf("WARNING: Over budget: %s", warning)
 	}
 
-	log.Printf("CSV saved to: %s/logs/", logBasePath)
+	log.Printf("Cost log saved to: %s/", filepath.Dir(result.OutputPath))
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -9,10 +11,13 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
 	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -21,9 +26,16 @@ import (
 // Configuration constants
 const (
 	UIMessagesFile = "ui_messages.json"
-	DebounceDelay  = 1 // seconds
 )
 
+// costStore is the cost database shared by the file watcher and MCP tools,
+// nil when it could not be opened
+var costStore *store.Store
+
+// trackerConfig holds the settings loaded at startup from the config file,
+// environment and flags
+var trackerConfig = config.Default()
+
 // getGitHash returns the current git commit hash
 func getGitHash() string {
 	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
@@ -34,36 +46,28 @@ func getGitHash() string {
 	return strings.TrimSpace(string(output))
 }
 
-// GetClineTasksPath returns the path to Cline tasks directory
-func GetClineTasksPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Printf("Error getting home directory: %v", err)
-		return ""
-	}
-	return filepath.Join(homeDir, "Library", "Application Support", "Code", "User", "globalStorage", "saoudrizwan.claude-dev", "tasks")
-}
-
-// GetCurrentTaskID returns the most recent task ID
-func GetCurrentTaskID() (string, error) {
-	tasksPath := GetClineTasksPath()
-	entries, err := os.ReadDir(tasksPath)
-	if err != nil {
-		return "", err
-	}
-
+// GetCurrentTaskPath returns the UI messages file of the most recently
+// modified task across the configured tasks roots
+func GetCurrentTaskPath() (string, error) {
 	var latestTask string
 	var latestTime time.Time
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			info, err := entry.Info()
-			if err != nil {
-				continue
-			}
-			if info.ModTime().After(latestTime) {
-				latestTime = info.ModTime()
-				latestTask = entry.Name()
+	for _, root := range trackerConfig.TasksRoots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				info, err := entry.Info()
+				if err != nil {
+					continue
+				}
+				if info.ModTime().After(latestTime) {
+					latestTime = info.ModTime()
+					latestTask = filepath.Join(root, entry.Name())
+				}
 			}
 		}
 	}
@@ -72,7 +76,7 @@ func GetCurrentTaskID() (string, error) {
 		return "", fmt.Errorf("no tasks found")
 	}
 
-	return latestTask, nil
+	return filepath.Join(latestTask, UIMessagesFile), nil
 }
 
 // FileWatcher handles monitoring of Cline task files
@@ -80,6 +84,13 @@ type FileWatcher struct {
 	watcher       *fsnotify.Watcher
 	debounceTimer map[string]*time.Timer
 	stopChan      chan bool
+
+	// loopWarnings logs when a task starts or stops looping
+	loopWarnings uilogparser.LoopWarnings
+
+	// budgetWarnings remembers the budgets last reported exceeded per task file
+	budgetWarnings map[string]string
+	budgetMu       sync.Mutex
 }
 
 // NewFileWatcher creates a new file watcher instance
@@ -90,27 +101,39 @@ func NewFileWatcher() (*FileWatcher, error) {
 	}
 
 	return &FileWatcher{
-		watcher:       watcher,
-		debounceTimer: make(map[string]*time.Timer),
-		stopChan:      make(chan bool),
+		watcher:        watcher,
+		debounceTimer:  make(map[string]*time.Timer),
+		stopChan:       make(chan bool),
+		budgetWarnings: make(map[string]string),
 	}, nil
 }
 
-// Start begins monitoring the Cline tasks directory
+// Start begins monitoring the configured Cline tasks directories that exist
 func (fw *FileWatcher) Start() error {
-	// Add the Cline tasks directory to the watcher
-	err := fw.watcher.Add(GetClineTasksPath())
-	if err != nil {
-		return fmt.Errorf("failed to watch Cline tasks directory: %v", err)
-	}
+	watched := 0
+	for _, root := range trackerConfig.TasksRoots {
+		// Add the tasks directory to the watcher
+		if err := fw.watcher.Add(root); err != nil {
+			log.Printf("Warning: failed to watch tasks directory %s: %v", root, err)
+			continue
+		}
+		watched++
 
-	// Also watch existing task subdirectories
-	err = fw.watchExistingTasks()
-	if err != nil {
-		log.Printf("Warning: failed to watch some existing tasks: %v", err)
-	}
+		// Also watch existing task subdirectories
+		if err := fw.watchExistingTasks(root); err != nil {
+			log.Printf("Warning: failed to watch some existing tasks: %v", err)
+		}
 
-	log.Printf("Started watching Cline tasks directory: %s", GetClineTasksPath())
+		log.Printf("Started watching %s tasks directory: %s", uilogparser.SourceForTasksRoot(root).Name, root)
+	}
+	// Keep serving the MCP tools on machines without Cline tasks
+	if watched == 0 {
+		searched := trackerConfig.TasksRoots
+		if len(searched) == 0 {
+			searched = config.CandidateAgentTasksRoots()
+		}
+		log.Printf("Warning: no tasks directory found, looked in: %s", strings.Join(searched, ", "))
+	}
 
 	go fw.watchLoop()
 	return nil
@@ -122,9 +145,9 @@ func (fw *FileWatcher) Stop() {
 	fw.watcher.Close()
 }
 
-// watchExistingTasks adds existing task directories to the watcher
-func (fw *FileWatcher) watchExistingTasks() error {
-	pattern := filepath.Join(GetClineTasksPath(), "*")
+// watchExistingTasks adds the existing task directories of a tasks root to the watcher
+func (fw *FileWatcher) watchExistingTasks(root string) error {
+	pattern := filepath.Join(root, "*")
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return err
@@ -193,7 +216,7 @@ func (fw *FileWatcher) debounceProcessing(filePath string) {
 	}
 
 	// Create new timer
-	fw.debounceTimer[filePath] = time.AfterFunc(DebounceDelay*time.Second, func() {
+	fw.debounceTimer[filePath] = time.AfterFunc(trackerConfig.Debounce, func() {
 		fw.processFile(filePath)
 		delete(fw.debounceTimer, filePath)
 	})
@@ -203,83 +226,37 @@ func (fw *FileWatcher) debounceProcessing(filePath string) {
 func (fw *FileWatcher) processFile(filePath string) {
 	log.Printf("Processing file change: %s", filePath)
 
-	// Try to detect the repository root where Cline is working
-	repoRoot, err := detectRepositoryRoot(filePath)
-	if err != nil {
-		log.Printf("Warning: Could not detect repository root (%v), falling back to current directory", err)
-		// Fallback to current working directory
-		repoRoot, err = os.Getwd()
-		if err != nil {
-			log.Printf("Error getting current working directory: %v", err)
-			return
-		}
+	// Write the logs to the repository of the task's working directory
+	result, err := uilogparser.ProcessUILogAutoAt(filePath, trackerConfig.Options)
+	if errors.Is(err, uilogparser.ErrTrackingDisabled) {
+		log.Printf("Skipping %s: %v", filePath, err)
+		return
 	}
-
-	// Create the target log directory: {repoRoot}/ui-log-parser
-	logBasePath := filepath.Join(repoRoot, "ui-log-parser")
-
-	// Use the existing function to process and generate CSV
-	err = uilogparser.ProcessUILogToCSVAutoAt(filePath, logBasePath)
 	if err != nil {
 		log.Printf("Error processing file %s: %v", filePath, err)
 		return
 	}
+	fw.loopWarnings.Warn(filePath, result.Report)
+	recordLedger(result.Ledger)
+	fw.warnIfOverBudget(filePath, result)
 
 	log.Printf("Successfully processed and generated CSV for: %s", filePath)
-	log.Printf("CSV saved to: %s/logs/", logBasePath)
-}
-
-// detectRepositoryRoot attempts to find the repository root where Cline is working
-func detectRepositoryRoot(taskFilePath string) (string, error) {
-	// Extract working directory from the ui_messages.json file
-	if workingDir := extractWorkingDirectoryFromTask(taskFilePath); workingDir != "" {
-		return workingDir, nil
-	}
-
-	return "", fmt.Errorf("could not extract working directory from task context")
-}
-
-// extractWorkingDirectoryFromTask extracts the working directory from ui_messages.json
-func extractWorkingDirectoryFromTask(taskFilePath string) string {
-	file, err := os.Open(taskFilePath)
-	if err != nil {
-		return ""
-	}
-	defer file.Close()
-
-	// Read the file content
-	content := make([]byte, 10240) // Read first 10KB to find the working directory
-	n, err := file.Read(content)
-	if err != nil && n == 0 {
-		return ""
-	}
-
-	contentStr := string(content[:n])
-
-	// Look for the pattern "# Current Working Directory (/path/to/directory)"
-	workingDirPattern := "# Current Working Directory ("
-	startIdx := strings.Index(contentStr, workingDirPattern)
-	if startIdx == -1 {
-		return ""
-	}
-
-	// Find the start of the path
-	pathStart := startIdx + len(workingDirPattern)
-
-	// Find the end of the path (closing parenthesis)
-	pathEnd := strings.Index(contentStr[pathStart:], ")")
-	if pathEnd == -1 {
-		return ""
-	}
-
-	workingDir := contentStr[pathStart : pathStart+pathEnd]
-	return workingDir
+	log.Printf("CSV saved to: %s/", filepath.Dir(result.OutputPath))
 }
 
 func main() {
 	// Set up logging.
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	// Settings come from the config file, then the environment, then flags
+	cfg, err := config.Load(config.PathFromArgs(os.Args[1:]))
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	trackerConfig = cfg
+
 	// Create implementation
 	impl := &mcp.Implementation{}
 
@@ -297,12 +274,103 @@ func main() {
 					Type:        "string",
 					Description: "Optional path to ui_messages.json file. If not provided, uses current task.",
 				},
+				"format": {
+					Type:        "string",
+					Description: "Optional output format. Defaults to the COST_TRACKER_FORMAT environment variable, or csv.",
+					Enum:        []any{uilogparser.FormatCSV, uilogparser.FormatJSONL, uilogparser.FormatBundle, uilogparser.FormatAnonymized},
+				},
+				"profile": {
+					Type:        "string",
+					Description: "Optional CSV column profile. Defaults to the COST_TRACKER_CSV_PROFILE environment variable, or standard.",
+					Enum:        []any{uilogparser.ProfileMinimal, uilogparser.ProfileStandard, uilogparser.ProfileForensic},
+				},
+				"currency": {
+					Type:        "string",
+					Description: "Optional display currency, e.g. AUD. Defaults to the COST_TRACKER_CURRENCY environment variable, or USD. Needs COST_TRACKER_FX_RATES.",
+				},
 			},
 		},
 	}
 
 	server.AddTool(tool, handleGenerateCSV)
 
+	// Add query_costs tool
+	server.AddTool(&mcp.Tool{
+		Name:        "query_costs",
+		Description: "Summarise spend per repository, branch and commit across all tasks recorded in the cost database",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"since": {
+					Type:        "string",
+					Description: "Optional start of the window: days (7d), duration (12h), date (2025-07-28) or RFC 3339 time. Defaults to 7d.",
+				},
+				"currency": {
+					Type:        "string",
+					Description: "Optional display currency, e.g. AUD. Defaults to the COST_TRACKER_CURRENCY environment variable, or USD. Needs COST_TRACKER_FX_RATES.",
+				},
+			},
+		},
+	}, handleQueryCosts)
+
+	// Add generate_html_report tool
+	server.AddTool(&mcp.Tool{
+		Name:        "generate_html_report",
+		Description: "Generate a self-contained HTML cost dashboard covering every recorded task of a repository",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"repository": {
+					Type:        "string",
+					Description: "Repository name, as listed by query_costs",
+				},
+				"output_path": {
+					Type:        "string",
+					Description: "Optional output file. Defaults to the repository's ui-log-parser/logs directory.",
+				},
+				"currency": {
+					Type:        "string",
+					Description: "Optional display currency, e.g. AUD. Defaults to the COST_TRACKER_CURRENCY environment variable, or USD. Needs COST_TRACKER_FX_RATES.",
+				},
+			},
+			Required: []string{"repository"},
+		},
+	}, handleGenerateHTMLReport)
+
+	// Add export_bundle tool
+	server.AddTool(&mcp.Tool{
+		Name:        "export_bundle",
+		Description: "Export recorded tasks as normalized CSV tables (tasks, api_requests, tool_calls, messages) with a schema.json",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"output_dir": {
+					Type:        "string",
+					Description: "Directory to write the bundle to",
+				},
+				"since": {
+					Type:        "string",
+					Description: "Optional start of the window: days (7d), duration (12h), date (2025-07-28) or RFC 3339 time. Defaults to every recorded task.",
+				},
+				"anonymize": {
+					Type:        "boolean",
+					Description: "Optional. Strip free text, hash task IDs, repository names and paths with COST_TRACKER_ANON_SALT and bucket timestamps.",
+				},
+			},
+			Required: []string{"output_dir"},
+		},
+	}, handleExportBundle)
+
+	// Open the cost database, tracking continues without it on failure
+	if trackerConfig.Database != "" {
+		costStore, err = store.Open(trackerConfig.Database)
+		if err != nil {
+			log.Printf("Warning: cost database unavailable: %v", err)
+		} else {
+			defer costStore.Close()
+		}
+	}
+
 	// Start file watcher in background
 	fileWatcher, err := NewFileWatcher()
 	if err != nil {
@@ -370,11 +438,11 @@ func HandleGenerateCSV(params map[string]interface{}) (string, error) {
 	filePath, ok := params["file_path"].(string)
 	if !ok || filePath == "" {
 		// If no file path provided, use the current task
-		currentTaskID, err := GetCurrentTaskID()
+		var err error
+		filePath, err = GetCurrentTaskPath()
 		if err != nil {
 			return "", err
 		}
-		filePath = GetClineTasksPath() + "/" + currentTaskID + "/" + UIMessagesFile
 	}
 
 	// Check if file exists
@@ -382,27 +450,233 @@ func HandleGenerateCSV(params map[string]interface{}) (string, error) {
 		return "", err
 	}
 
-	// Try to detect the repository root where Cline is working
-	repoRoot, err := detectRepositoryRoot(filePath)
+	// Use the configured output format unless the caller asked for another
+	opts := trackerConfig.Options
+	if format, ok := params["format"].(string); ok && format != "" {
+		opts.Format = format
+	}
+	if profile, ok := params["profile"].(string); ok && profile != "" {
+		opts.CSVProfile = profile
+	}
+	if currency, ok := params["currency"].(string); ok && currency != "" {
+		opts.Currency = currency
+	}
+
+	// Write the logs to the repository of the task's working directory
+	result, err := uilogparser.ProcessUILogAutoAt(filePath, opts)
+	if err != nil {
+		return "", err
+	}
+	recordLedger(result.Ledger)
+
+	// Extract task ID for response
+	taskID := uilogparser.ExtractTaskID(filePath)
+
+	return "Successfully processed task " + taskID + " and generated CSV file at " + result.OutputPath, nil
+}
+
+// warnIfOverBudget logs a warning when a processed task puts a configured
+// budget over its limit, once per change in the budgets exceeded
+func (fw *FileWatcher) warnIfOverBudget(filePath string, result *uilogparser.TaskResult) {
+	budgets := trackerConfig.Budgets.ForRepository(result.RepoConfig)
+	warnings, err := budgets.Check(result.Ledger, costStore)
 	if err != nil {
-		// Fallback to current working directory
-		repoRoot, err = os.Getwd()
-		if err != nil {
+		log.Printf("Warning: failed to check budgets: %v", err)
+	}
+
+	var exceeded []string
+	for _, warning := range warnings {
+		exceeded = append(exceeded, warning.Budget)
+	}
+	key := strings.Join(exceeded, ",")
+
+	fw.budgetMu.Lock()
+	defer fw.budgetMu.Unlock()
+
+	if key == fw.budgetWarnings[filePath] {
+		return
+	}
+	fw.budgetWarnings[filePath] = key
+
+	for _, warning := range warnings {
+		log.Printf("WARNING: Over budget: %s", warning)
+	}
+}
+
+// handleQueryCosts handles the query_costs tool call
+func handleQueryCosts(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+	arguments := make(map[string]interface{})
+	if params.Arguments != nil {
+		arguments = params.Arguments
+	}
+
+	result, err := HandleQueryCosts(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: result,
+			},
+		},
+	}, nil
+}
+
+// HandleQueryCosts summarises spend per repository, branch and commit from the
+// cost database
+func HandleQueryCosts(params map[string]interface{}) (string, error) {
+	if costStore == nil {
+		return "", fmt.Errorf("cost database is not available")
+	}
+
+	sinceValue, _ := params["since"].(string)
+	since, err := store.ParseSince(sinceValue, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	converter, _, err := displayCurrency(params)
+	if err != nil {
+		return "", err
+	}
+	return costStore.SpendSummary(since, converter)
+}
+
+// displayCurrency returns the converter for the currency a tool call asked
+// for, or the configured one, and the time zone dates are taken in. Tasks of a
+// repository can report different zones, so "task" falls back to UTC.
+func displayCurrency(params map[string]interface{}) (*uilogparser.CurrencyConverter, *time.Location, error) {
+	opts := trackerConfig.Options
+	if currency, ok := params["currency"].(string); ok && currency != "" {
+		opts.Currency = currency
+	}
+
+	loc, err := uilogparser.ResolveLocation(opts.TimeZone, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	converter, err := uilogparser.NewCurrencyConverter(opts.Currency, opts.FXRatesPath, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return converter, loc, nil
+}
+
+// handleGenerateHTMLReport handles the generate_html_report tool call
+func handleGenerateHTMLReport(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+	arguments := make(map[string]interface{})
+	if params.Arguments != nil {
+		arguments = params.Arguments
+	}
+
+	result, err := HandleGenerateHTMLReport(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: result,
+			},
+		},
+	}, nil
+}
+
+// HandleGenerateHTMLReport writes the HTML dashboard of a repository from the cost database
+func HandleGenerateHTMLReport(params map[string]interface{}) (string, error) {
+	if costStore == nil {
+		return "", fmt.Errorf("cost database is not available")
+	}
+
+	repository, _ := params["repository"].(string)
+	if repository == "" {
+		return "", fmt.Errorf("repository is required")
+	}
+	outputPath, _ := params["output_path"].(string)
+
+	converter, loc, err := displayCurrency(params)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := costStore.WriteRepositoryHTMLReport(repository, outputPath, trackerConfig.Options, uilogparser.HTMLReportOptions{Location: loc, Converter: converter})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("HTML report generated successfully: %s", path), nil
+}
+
+// handleExportBundle handles the export_bundle tool call
+func handleExportBundle(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+	arguments := make(map[string]interface{})
+	if params.Arguments != nil {
+		arguments = params.Arguments
+	}
+
+	result, err := HandleExportBundle(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: result,
+			},
+		},
+	}, nil
+}
+
+// HandleExportBundle writes recorded tasks from the cost database as a bundle
+func HandleExportBundle(params map[string]interface{}) (string, error) {
+	if costStore == nil {
+		return "", fmt.Errorf("cost database is not available")
+	}
+
+	outputDir, _ := params["output_dir"].(string)
+	if outputDir == "" {
+		return "", fmt.Errorf("output_dir is required")
+	}
+
+	var since time.Time
+	if sinceValue, _ := params["since"].(string); sinceValue != "" {
+		var err error
+		if since, err = store.ParseSince(sinceValue, time.Now()); err != nil {
 			return "", err
 		}
 	}
 
-	// Create the target log directory: {repoRoot}/ui-log-parser
-	logBasePath := repoRoot + "/ui-log-parser"
-
-	// Use the existing function to process and generate CSV
-	err = uilogparser.ProcessUILogToCSVAutoAt(filePath, logBasePath)
+	ledgers, err := costStore.Ledgers(since)
 	if err != nil {
 		return "", err
 	}
 
-	// Extract task ID for response
-	taskID := uilogparser.ExtractTaskID(filePath)
+	// Anonymized bundles use the configured salt and bucket
+	if anonymize, _ := params["anonymize"].(bool); anonymize {
+		opts := trackerConfig.Options
+		var anonymizer *uilogparser.Anonymizer
+		if anonymizer, err = uilogparser.NewAnonymizer(opts.AnonymizeSalt, opts.AnonymizeBucket); err != nil {
+			return "", err
+		}
+		err = uilogparser.WriteAnonymizedBundle(outputDir, ledgers, anonymizer)
+	} else {
+		err = uilogparser.WriteBundle(outputDir, ledgers)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Exported %d tasks to %s", len(ledgers), outputDir), nil
+}
 
-	return "Successfully processed task " + taskID + " and generated CSV file at " + logBasePath + "/logs/", nil
+// recordLedger upserts a processed task into the cost database
+func recordLedger(ledger *uilogparser.Ledger) {
+	if costStore == nil {
+		return
+	}
+	if err := costStore.UpsertLedger(ledger); err != nil {
+		log.Printf("Warning: failed to store task %s in cost database: %v", ledger.Task.TaskID, err)
+	}
 }
@@ -1,61 +1,57 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
 )
 
 const (
-	// ClineTasksPath is the base path where Cline stores task data
-	ClineTasksPath = "/Users/emma/Library/Application Support/Code/User/globalStorage/saoudrizwan.claude-dev/tasks"
-
 	// UIMessagesFile is the filename for UI messages in each task directory
 	UIMessagesFile = "ui_messages.json"
-
-	// DebounceDelay is the delay in seconds before processing file changes
-	DebounceDelay = 1
-
-	// LogsDirectory is where CSV files are saved
-	LogsDirectory = "logs"
 )
 
-// GetClineTasksPath returns the path to Cline tasks directory
-func GetClineTasksPath() string {
-	return ClineTasksPath
-}
-
-// GetUIMessagesPattern returns the glob pattern for UI messages files
-func GetUIMessagesPattern() string {
-	return filepath.Join(ClineTasksPath, "*", UIMessagesFile)
-}
+// costStore is the cost database shared by the file watcher and MCP tools,
+// nil when it could not be opened
+var costStore *store.Store
 
-// EnsureLogsDirectory creates the logs directory if it doesn't exist
-func EnsureLogsDirectory() error {
-	return os.MkdirAll(LogsDirectory, 0755)
-}
-
-// GetCurrentTaskID returns the most recent task ID
-func GetCurrentTaskID() (string, error) {
-	entries, err := os.ReadDir(ClineTasksPath)
-	if err != nil {
-		return "", err
-	}
+// trackerConfig holds the settings loaded at startup from the config file,
+// environment and flags
+var trackerConfig = config.Default()
 
+// GetCurrentTaskPath returns the UI messages file of the most recently
+// modified task across the configured tasks roots
+func GetCurrentTaskPath() (string, error) {
 	var latestTask string
-	var latestTime int64
+	var latestTime time.Time
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			info, err := entry.Info()
-			if err != nil {
-				continue
-			}
-			if info.ModTime().Unix() > latestTime {
-				latestTime = info.ModTime().Unix()
-				latestTask = entry.Name()
+	for _, root := range trackerConfig.TasksRoots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				info, err := entry.Info()
+				if err != nil {
+					continue
+				}
+				if info.ModTime().After(latestTime) {
+					latestTime = info.ModTime()
+					latestTask = filepath.Join(root, entry.Name())
+				}
 			}
 		}
 	}
 
-	return latestTask, nil
+	if latestTask == "" {
+		return "", fmt.Errorf("no tasks found")
+	}
+
+	return filepath.Join(latestTask, UIMessagesFile), nil
 }
@@ -1,175 +1,31 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/pkg/ui-log-parser"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
 )
 
-// detectRepositoryRoot attempts to find the repository root where Cline is working
-func detectRepositoryRoot(taskFilePath string) (string, error) {
-	log.Printf("DEBUG: Starting repository detection for task file: %s", taskFilePath)
-
-	// Extract working directory from the ui_messages.json file
-	if workingDir := extractWorkingDirectoryFromTask(taskFilePath); workingDir != "" {
-		log.Printf("DEBUG: Extracted working directory from task: %s", workingDir)
-		log.Printf("DEBUG: Using extracted working directory directly as repository root: %s", workingDir)
-		return workingDir, nil
-	}
-
-	log.Printf("DEBUG: Could not extract working directory from task context")
-	return "", fmt.Errorf("could not extract working directory from task context")
-}
-
-// extractWorkingDirectoryFromTask extracts the working directory from ui_messages.json
-func extractWorkingDirectoryFromTask(taskFilePath string) string {
-	log.Printf("DEBUG: Extracting working directory from: %s", taskFilePath)
-
-	file, err := os.Open(taskFilePath)
-	if err != nil {
-		log.Printf("DEBUG: Error opening task file: %v", err)
-		return ""
-	}
-	defer file.Close()
-
-	// Read the file content
-	content := make([]byte, 10240) // Read first 10KB to find the working directory
-	n, err := file.Read(content)
-	if err != nil && n == 0 {
-		log.Printf("DEBUG: Error reading task file: %v", err)
-		return ""
-	}
-
-	contentStr := string(content[:n])
-	log.Printf("DEBUG: Read %d bytes from task file", n)
-
-	// Look for the pattern "# Current Working Directory (/path/to/directory)"
-	workingDirPattern := "# Current Working Directory ("
-	startIdx := strings.Index(contentStr, workingDirPattern)
-	if startIdx == -1 {
-		log.Printf("DEBUG: Working directory pattern not found")
-		return ""
-	}
-
-	// Find the start of the path
-	pathStart := startIdx + len(workingDirPattern)
-
-	// Find the end of the path (closing parenthesis)
-	pathEnd := strings.Index(contentStr[pathStart:], ")")
-	if pathEnd == -1 {
-		log.Printf("DEBUG: Could not find end of working directory path")
-		return ""
-	}
-
-	workingDir := contentStr[pathStart : pathStart+pathEnd]
-	log.Printf("DEBUG: Extracted working directory: %s", workingDir)
-	return workingDir
-}
-
-// findRepoRootFromPath walks up the directory tree to find a repository root
-func findRepoRootFromPath(startPath string) string {
-	currentPath := startPath
-
-	for {
-		if repoRoot := findRepoRoot(currentPath); repoRoot != "" {
-			return repoRoot
-		}
-
-		parentPath := filepath.Dir(currentPath)
-		if parentPath == currentPath {
-			// Reached the root directory
-			break
-		}
-		currentPath = parentPath
-	}
-
-	return ""
-}
-
-// findRepoRoot checks if a directory is a repository root
-func findRepoRoot(path string) string {
-	// Check for common repository markers
-	markers := []string{".git", "go.mod", "package.json", ".gitignore"}
-
-	for _, marker := range markers {
-		if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
-			// Found a repository marker, this is likely a repo root
-			return path
-		}
-	}
-
-	return ""
-}
-
-// findRecentReposInPath finds repositories in the given path
-func findRecentReposInPath(basePath string) []string {
-	var repos []string
-
-	entries, err := os.ReadDir(basePath)
-	if err != nil {
-		return repos
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			dirPath := filepath.Join(basePath, entry.Name())
-			if repoRoot := findRepoRoot(dirPath); repoRoot != "" {
-				repos = append(repos, repoRoot)
-			}
-		}
-	}
-
-	return repos
-}
-
-// findRecentReposInPathRecursive finds repositories recursively up to maxDepth levels
-func findRecentReposInPathRecursive(basePath string, maxDepth int) []string {
-	var repos []string
-
-	if maxDepth <= 0 {
-		log.Printf("DEBUG: Reached max depth, stopping recursion at: %s", basePath)
-		return repos
-	}
-
-	log.Printf("DEBUG: Searching recursively in: %s (depth: %d)", basePath, maxDepth)
-	entries, err := os.ReadDir(basePath)
-	if err != nil {
-		log.Printf("DEBUG: Error reading directory %s: %v", basePath, err)
-		return repos
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			dirPath := filepath.Join(basePath, entry.Name())
-			log.Printf("DEBUG: Checking directory: %s", dirPath)
-
-			// Check if this directory is a repository
-			if repoRoot := findRepoRoot(dirPath); repoRoot != "" {
-				log.Printf("DEBUG: Found repository: %s", repoRoot)
-				repos = append(repos, repoRoot)
-			}
-
-			// Recursively search subdirectories
-			subRepos := findRecentReposInPathRecursive(dirPath, maxDepth-1)
-			repos = append(repos, subRepos...)
-		}
-	}
-
-	log.Printf("DEBUG: Found %d repositories in %s", len(repos), basePath)
-	return repos
-}
-
 // FileWatcher handles monitoring of Cline task files
 type FileWatcher struct {
 	watcher       *fsnotify.Watcher
 	debounceTimer map[string]*time.Timer
 	stopChan      chan bool
+
+	// loopWarnings logs when a task starts or stops looping
+	loopWarnings uilogparser.LoopWarnings
+
+	// budgetWarnings remembers the budgets last reported exceeded per task file
+	budgetWarnings map[string]string
+	budgetMu       sync.Mutex
 }
 
 // NewFileWatcher creates a new file watcher instance
@@ -180,27 +36,39 @@ func NewFileWatcher() (*FileWatcher, error) {
 	}
 
 	return &FileWatcher{
-		watcher:       watcher,
-		debounceTimer: make(map[string]*time.Timer),
-		stopChan:      make(chan bool),
+		watcher:        watcher,
+		debounceTimer:  make(map[string]*time.Timer),
+		stopChan:       make(chan bool),
+		budgetWarnings: make(map[string]string),
 	}, nil
 }
 
-// Start begins monitoring the Cline tasks directory
+// Start begins monitoring the configured Cline tasks directories that exist
 func (fw *FileWatcher) Start() error {
-	// Add the Cline tasks directory to the watcher
-	err := fw.watcher.Add(GetClineTasksPath())
-	if err != nil {
-		return fmt.Errorf("failed to watch Cline tasks directory: %v", err)
-	}
+	watched := 0
+	for _, root := range trackerConfig.TasksRoots {
+		// Add the tasks directory to the watcher
+		if err := fw.watcher.Add(root); err != nil {
+			log.Printf("Warning: failed to watch tasks directory %s: %v", root, err)
+			continue
+		}
+		watched++
 
-	// Also watch existing task subdirectories
-	err = fw.watchExistingTasks()
-	if err != nil {
-		log.Printf("Warning: failed to watch some existing tasks: %v", err)
-	}
+		// Also watch existing task subdirectories
+		if err := fw.watchExistingTasks(root); err != nil {
+			log.Printf("Warning: failed to watch some existing tasks: %v", err)
+		}
 
-	log.Printf("Started watching Cline tasks directory: %s", GetClineTasksPath())
+		log.Printf("Started watching %s tasks directory: %s", uilogparser.SourceForTasksRoot(root).Name, root)
+	}
+	// Keep serving the MCP tools on machines without Cline tasks
+	if watched == 0 {
+		searched := trackerConfig.TasksRoots
+		if len(searched) == 0 {
+			searched = config.CandidateAgentTasksRoots()
+		}
+		log.Printf("Warning: no tasks directory found, looked in: %s", strings.Join(searched, ", "))
+	}
 
 	go fw.watchLoop()
 	return nil
@@ -212,9 +80,9 @@ func (fw *FileWatcher) Stop() {
 	fw.watcher.Close()
 }
 
-// watchExistingTasks adds existing task directories to the watcher
-func (fw *FileWatcher) watchExistingTasks() error {
-	pattern := filepath.Join(GetClineTasksPath(), "*")
+// watchExistingTasks adds the existing task directories of a tasks root to the watcher
+func (fw *FileWatcher) watchExistingTasks(root string) error {
+	pattern := filepath.Join(root, "*")
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return err
@@ -286,7 +154,7 @@ func (fw *FileWatcher) debounceProcessing(filePath string) {
 	}
 
 	// Create new timer
-	fw.debounceTimer[filePath] = time.AfterFunc(DebounceDelay*time.Second, func() {
+	fw.debounceTimer[filePath] = time.AfterFunc(trackerConfig.Debounce, func() {
 		fw.processFile(filePath)
 		delete(fw.debounceTimer, filePath)
 	})
@@ -296,31 +164,48 @@ func (fw *FileWatcher) debounceProcessing(filePath string) {
 func (fw *FileWatcher) processFile(filePath string) {
 	log.Printf("Processing file change: %s", filePath)
 
-	// Try to detect the repository root where Cline is working
-	repoRoot, err := detectRepositoryRoot(filePath)
+	// Write the logs to the repository of the task's working directory
+	result, err := uilogparser.ProcessUILogAutoAt(filePath, trackerConfig.Options)
+	if errors.Is(err, uilogparser.ErrTrackingDisabled) {
+		log.Printf("Skipping %s: %v", filePath, err)
+		return
+	}
 	if err != nil {
-		log.Printf("Warning: Could not detect repository root (%v), falling back to MCP server directory", err)
-		// Fallback to current working directory (MCP server directory)
-		repoRoot, err = os.Getwd()
-		if err != nil {
-			log.Printf("Error getting current working directory: %v", err)
-			return
-		}
+		log.Printf("Error processing file %s: %v", filePath, err)
+		return
 	}
+	fw.loopWarnings.Warn(filePath, result.Report)
+	recordLedger(result.Ledger)
+	fw.warnIfOverBudget(filePath, result)
 
-	// Create the target log directory: {repoRoot}/ui-log-parser/logs
-	logBasePath := filepath.Join(repoRoot, "ui-log-parser")
-
-	log.Printf("DEBUG: Detected repository root: %s", repoRoot)
-	log.Printf("DEBUG: Using ProcessUILogToCSVAutoAt with basePath: %s", logBasePath)
+	log.Printf("Successfully processed and generated CSV for: %s", filePath)
+	log.Printf("CSV saved to: %s/", filepath.Dir(result.OutputPath))
+}
 
-	// Use the new function that creates logs directory in the detected repository
-	err = uilogparser.ProcessUILogToCSVAutoAt(filePath, logBasePath)
+// warnIfOverBudget logs a warning when a processed task puts a configured
+// budget over its limit, once per change in the budgets exceeded
+func (fw *FileWatcher) warnIfOverBudget(filePath string, result *uilogparser.TaskResult) {
+	budgets := trackerConfig.Budgets.ForRepository(result.RepoConfig)
+	warnings, err := budgets.Check(result.Ledger, costStore)
 	if err != nil {
-		log.Printf("Error processing file %s: %v", filePath, err)
+		log.Printf("Warning: failed to check budgets: %v", err)
+	}
+
+	var exceeded []string
+	for _, warning := range warnings {
+		exceeded = append(exceeded, warning.Budget)
+	}
+	key := strings.Join(exceeded, ",")
+
+	fw.budgetMu.Lock()
+	defer fw.budgetMu.Unlock()
+
+	if key == fw.budgetWarnings[filePath] {
 		return
 	}
+	fw.budgetWarnings[filePath] = key
 
-	log.Printf("Successfully processed and generated CSV for: %s", filePath)
-	log.Printf("CSV saved to: %s/logs/", logBasePath)
+	for _, warning := range warnings {
+		log.Printf("WARNING: Over budget: %s", warning)
+	}
 }
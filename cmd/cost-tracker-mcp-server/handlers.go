@@ -2,10 +2,12 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"os"
-	"path/filepath"
+	"time"
 
-	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/pkg/ui-log-parser"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
 )
 
 // MCPResponse represents an MCP tool response
@@ -25,11 +27,11 @@ func HandleGenerateCSV(params map[string]interface{}) (*MCPResponse, error) {
 	filePath, ok := params["file_path"].(string)
 	if !ok || filePath == "" {
 		// If no file path provided, use the current task
-		currentTaskID, err := GetCurrentTaskID()
+		var err error
+		filePath, err = GetCurrentTaskPath()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get current task ID: %v", err)
+			return nil, fmt.Errorf("failed to get current task: %v", err)
 		}
-		filePath = filepath.Join(GetClineTasksPath(), currentTaskID, UIMessagesFile)
 	}
 
 	// Check if file exists
@@ -37,24 +39,24 @@ func HandleGenerateCSV(params map[string]interface{}) (*MCPResponse, error) {
 		return nil, fmt.Errorf("file does not exist: %s", filePath)
 	}
 
-	// Try to detect the repository root where Cline is working
-	repoRoot, err := detectRepositoryRoot(filePath)
-	if err != nil {
-		// Fallback to current working directory (MCP server directory)
-		repoRoot, err = os.Getwd()
-		if err != nil {
-			return nil, fmt.Errorf("error getting current working directory: %v", err)
-		}
+	// Use the configured output format unless the caller asked for another
+	opts := trackerConfig.Options
+	if format, ok := params["format"].(string); ok && format != "" {
+		opts.Format = format
+	}
+	if profile, ok := params["profile"].(string); ok && profile != "" {
+		opts.CSVProfile = profile
+	}
+	if currency, ok := params["currency"].(string); ok && currency != "" {
+		opts.Currency = currency
 	}
 
-	// Create the target log directory: {repoRoot}/ui-log-parser
-	logBasePath := filepath.Join(repoRoot, "ui-log-parser")
-
-	// Use the new function that creates logs directory in the detected repository
-	err = uilogparser.ProcessUILogToCSVAutoAt(filePath, logBasePath)
+	// Write the logs to the repository of the task's working directory
+	result, err := uilogparser.ProcessUILogAutoAt(filePath, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process file: %v", err)
 	}
+	recordLedger(result.Ledger)
 
 	// Extract task ID for response
 	taskID := uilogparser.ExtractTaskID(filePath)
@@ -63,8 +65,152 @@ func HandleGenerateCSV(params map[string]interface{}) (*MCPResponse, error) {
 		Content: []MCPContent{
 			{
 				Type: "text",
-				Text: fmt.Sprintf("Successfully processed task %s and generated CSV file at %s/logs/", taskID, logBasePath),
+				Text: fmt.Sprintf("Successfully processed task %s and generated CSV file at %s", taskID, result.OutputPath),
 			},
 		},
 	}, nil
 }
+
+// HandleQueryCosts summarises spend per repository, branch and commit from the
+// cost database
+func HandleQueryCosts(params map[string]interface{}) (*MCPResponse, error) {
+	if costStore == nil {
+		return nil, fmt.Errorf("cost database is not available")
+	}
+
+	sinceValue, _ := params["since"].(string)
+	since, err := store.ParseSince(sinceValue, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	converter, _, err := displayCurrency(params)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := costStore.SpendSummary(since, converter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost database: %v", err)
+	}
+
+	return &MCPResponse{
+		Content: []MCPContent{
+			{
+				Type: "text",
+				Text: summary,
+			},
+		},
+	}, nil
+}
+
+// displayCurrency returns the converter for the currency a tool call asked
+// for, or the configured one, and the time zone dates are taken in. Tasks of a
+// repository can report different zones, so "task" falls back to UTC.
+func displayCurrency(params map[string]interface{}) (*uilogparser.CurrencyConverter, *time.Location, error) {
+	opts := trackerConfig.Options
+	if currency, ok := params["currency"].(string); ok && currency != "" {
+		opts.Currency = currency
+	}
+
+	loc, err := uilogparser.ResolveLocation(opts.TimeZone, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	converter, err := uilogparser.NewCurrencyConverter(opts.Currency, opts.FXRatesPath, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return converter, loc, nil
+}
+
+// HandleGenerateHTMLReport writes the HTML dashboard of a repository from the cost database
+func HandleGenerateHTMLReport(params map[string]interface{}) (*MCPResponse, error) {
+	if costStore == nil {
+		return nil, fmt.Errorf("cost database is not available")
+	}
+
+	repository, _ := params["repository"].(string)
+	if repository == "" {
+		return nil, fmt.Errorf("repository is required")
+	}
+	outputPath, _ := params["output_path"].(string)
+
+	converter, loc, err := displayCurrency(params)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := costStore.WriteRepositoryHTMLReport(repository, outputPath, trackerConfig.Options, uilogparser.HTMLReportOptions{Location: loc, Converter: converter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate HTML report: %v", err)
+	}
+
+	return &MCPResponse{
+		Content: []MCPContent{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("HTML report generated successfully: %s", path),
+			},
+		},
+	}, nil
+}
+
+// HandleExportBundle writes recorded tasks from the cost database as a bundle
+func HandleExportBundle(params map[string]interface{}) (*MCPResponse, error) {
+	if costStore == nil {
+		return nil, fmt.Errorf("cost database is not available")
+	}
+
+	outputDir, _ := params["output_dir"].(string)
+	if outputDir == "" {
+		return nil, fmt.Errorf("output_dir is required")
+	}
+
+	var since time.Time
+	if sinceValue, _ := params["since"].(string); sinceValue != "" {
+		var err error
+		if since, err = store.ParseSince(sinceValue, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	ledgers, err := costStore.Ledgers(since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost database: %v", err)
+	}
+
+	// Anonymized bundles use the configured salt and bucket
+	if anonymize, _ := params["anonymize"].(bool); anonymize {
+		opts := trackerConfig.Options
+		var anonymizer *uilogparser.Anonymizer
+		if anonymizer, err = uilogparser.NewAnonymizer(opts.AnonymizeSalt, opts.AnonymizeBucket); err != nil {
+			return nil, err
+		}
+		err = uilogparser.WriteAnonymizedBundle(outputDir, ledgers, anonymizer)
+	} else {
+		err = uilogparser.WriteBundle(outputDir, ledgers)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %v", err)
+	}
+
+	return &MCPResponse{
+		Content: []MCPContent{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Exported %d tasks to %s", len(ledgers), outputDir),
+			},
+		},
+	}, nil
+}
+
+// recordLedger upserts a processed task into the cost database
+func recordLedger(ledger *uilogparser.Ledger) {
+	if costStore == nil {
+		return
+	}
+	if err := costStore.UpsertLedger(ledger); err != nil {
+		log.Printf("Warning: failed to store task %s in cost database: %v", ledger.Task.TaskID, err)
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/config"
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
 )
 
 const VERSION = "v2.2.0-simplified"
@@ -15,6 +19,25 @@ func main() {
 
 	log.Printf("Cost Tracker MCP Server %s starting...", VERSION)
 
+	// Settings come from the config file, then the environment, then flags
+	cfg, err := config.Load(config.PathFromArgs(os.Args[1:]))
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	trackerConfig = cfg
+
+	// Open the cost database, tracking continues without it on failure
+	if trackerConfig.Database != "" {
+		costStore, err = store.Open(trackerConfig.Database)
+		if err != nil {
+			log.Printf("Warning: cost database unavailable: %v", err)
+		} else {
+			defer costStore.Close()
+		}
+	}
+
 	// Create MCP server
 	server, err := NewMCPServer()
 	if err != nil {
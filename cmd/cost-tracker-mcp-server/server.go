@@ -96,8 +96,6 @@ func (s *MCPServer) messageLoop() error {
 			log.Printf("Error handling message: %v", err)
 		}
 	}
-
-	return nil
 }
 
 // handleMessage processes an incoming MCP message
@@ -143,7 +141,82 @@ func (s *MCPServer) handleToolsList(id interface{}) error {
 								"type":        "string",
 								"description": "Path to ui_messages.json file (optional, defaults to current task)",
 							},
+							"format": map[string]interface{}{
+								"type":        "string",
+								"description": "Output format (optional, defaults to the COST_TRACKER_FORMAT environment variable, or csv)",
+								"enum":        []string{"csv", "jsonl", "bundle", "anonymized"},
+							},
+							"profile": map[string]interface{}{
+								"type":        "string",
+								"description": "CSV column profile (optional, defaults to the COST_TRACKER_CSV_PROFILE environment variable, or standard)",
+								"enum":        []string{"minimal", "standard", "forensic"},
+							},
+							"currency": map[string]interface{}{
+								"type":        "string",
+								"description": "Display currency, e.g. AUD (optional, defaults to the COST_TRACKER_CURRENCY environment variable, or USD; needs COST_TRACKER_FX_RATES)",
+							},
+						},
+					},
+				},
+				{
+					"name":        "query_costs",
+					"description": "Summarise spend per repository, branch and commit across all tasks recorded in the cost database",
+					"inputSchema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"since": map[string]interface{}{
+								"type":        "string",
+								"description": "Start of the window: days (7d), duration (12h), date (2025-07-28) or RFC 3339 time (optional, defaults to 7d)",
+							},
+							"currency": map[string]interface{}{
+								"type":        "string",
+								"description": "Display currency, e.g. AUD (optional, defaults to the COST_TRACKER_CURRENCY environment variable, or USD; needs COST_TRACKER_FX_RATES)",
+							},
+						},
+					},
+				},
+				{
+					"name":        "generate_html_report",
+					"description": "Generate a self-contained HTML cost dashboard covering every recorded task of a repository",
+					"inputSchema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"repository": map[string]interface{}{
+								"type":        "string",
+								"description": "Repository name, as listed by query_costs",
+							},
+							"output_path": map[string]interface{}{
+								"type":        "string",
+								"description": "Output file (optional, defaults to the repository's ui-log-parser/logs directory)",
+							},
+							"currency": map[string]interface{}{
+								"type":        "string",
+								"description": "Display currency, e.g. AUD (optional, defaults to the COST_TRACKER_CURRENCY environment variable, or USD; needs COST_TRACKER_FX_RATES)",
+							},
+						},
+						"required": []string{"repository"},
+					},
+				},
+				{
+					"name":        "export_bundle",
+					"description": "Export recorded tasks as normalized CSV tables (tasks, api_requests, tool_calls, messages) with a schema.json",
+					"inputSchema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"output_dir": map[string]interface{}{
+								"type":        "string",
+								"description": "Directory to write the bundle to",
+							},
+							"since": map[string]interface{}{
+								"type":        "string",
+								"description": "Start of the window: days (7d), duration (12h), date (2025-07-28) or RFC 3339 time (optional, defaults to every recorded task)",
+							},
+							"anonymize": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Strip free text, hash task IDs, repository names and paths with COST_TRACKER_ANON_SALT and bucket timestamps (optional)",
+							},
 						},
+						"required": []string{"output_dir"},
 					},
 				},
 			},
@@ -178,6 +251,12 @@ func (s *MCPServer) handleToolsCall(request map[string]interface{}) error {
 	switch name {
 	case "generate_csv":
 		response, err = HandleGenerateCSV(arguments)
+	case "query_costs":
+		response, err = HandleQueryCosts(arguments)
+	case "generate_html_report":
+		response, err = HandleGenerateHTMLReport(arguments)
+	case "export_bundle":
+		response, err = HandleExportBundle(arguments)
 	default:
 		return s.sendError(id, fmt.Sprintf("unknown tool: %s", name))
 	}
@@ -4,7 +4,9 @@ go 1.24.5
 
 require (
 	github.com/fsnotify/fsnotify v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/modelcontextprotocol/go-sdk v0.2.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// DefaultBudgetPeriod is the window repository spend is summed over
+const DefaultBudgetPeriod = "30d"
+
+// Budget names
+const (
+	BudgetTask       = "task"
+	BudgetRepository = "repository"
+)
+
+// Budgets are spend limits in USD, zero for no limit
+type Budgets struct {
+	// Task limits the cost of a single task
+	Task float64
+	// Repository limits the spend of each repository over Period
+	Repository float64
+	// Period is the repository budget window, in any form store.ParseSince
+	// accepts
+	Period string
+}
+
+// BudgetWarning reports spend over a budget
+type BudgetWarning struct {
+	// Budget is BudgetTask or BudgetRepository
+	Budget string
+	// Subject is the task ID or repository name
+	Subject string
+	Spend   float64
+	Limit   float64
+	// Since is the start of the repository budget window
+	Since time.Time
+}
+
+// String describes the warning for logs
+func (w BudgetWarning) String() string {
+	if w.Budget == BudgetRepository {
+		return fmt.Sprintf("repository %s spent $%.4f since %s, over its budget of $%.2f", w.Subject, w.Spend, w.Since.Format("2006-01-02"), w.Limit)
+	}
+	return fmt.Sprintf("task %s cost $%.4f, over its budget of $%.2f", w.Subject, w.Spend, w.Limit)
+}
+
+// Check returns a warning for each budget a processed task puts over its
+// limit. Repository spend is read from costStore, which should already hold
+// the task; the repository budget is skipped when costStore is nil.
+func (b Budgets) Check(ledger *uilogparser.Ledger, costStore *store.Store) ([]BudgetWarning, error) {
+	var warnings []BudgetWarning
+	task := ledger.Task
+	if b.Task > 0 && task.TotalCost > b.Task {
+		warnings = append(warnings, BudgetWarning{Budget: BudgetTask, Subject: task.TaskID, Spend: task.TotalCost, Limit: b.Task})
+	}
+
+	if b.Repository > 0 && costStore != nil && task.Repository != "" {
+		since, err := store.ParseSince(b.Period, time.Now())
+		if err != nil {
+			return warnings, fmt.Errorf("invalid budget period: %v", err)
+		}
+		spend, err := costStore.RepositoryCost(task.Repository, since)
+		if err != nil {
+			return warnings, err
+		}
+		if spend > b.Repository {
+			warnings = append(warnings, BudgetWarning{Budget: BudgetRepository, Subject: task.Repository, Spend: spend, Limit: b.Repository, Since: since})
+		}
+	}
+	return warnings, nil
+}
+
+// ForRepository returns the budgets with the repository budget set by a
+// repository config, when it sets one
+func (b Budgets) ForRepository(rc *uilogparser.RepositoryConfig) Budgets {
+	if rc != nil && rc.Budget > 0 {
+		b.Repository = rc.Budget
+	}
+	return b
+}
@@ -0,0 +1,280 @@
+// Package config loads the settings shared by the cost tracker binaries from
+// a YAML file, overridden by environment variables and then by flags.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables that override the config file
+const (
+	PathEnvVar             = "COST_TRACKER_CONFIG"
+	TasksRootsEnvVar       = "COST_TRACKER_TASKS_ROOTS"
+	DebounceEnvVar         = "COST_TRACKER_DEBOUNCE"
+	TaskBudgetEnvVar       = "COST_TRACKER_TASK_BUDGET"
+	RepositoryBudgetEnvVar = "COST_TRACKER_REPOSITORY_BUDGET"
+	BudgetPeriodEnvVar     = "COST_TRACKER_BUDGET_PERIOD"
+)
+
+// DefaultDebounce is how long the watcher waits after the last write to a
+// task before processing it
+const DefaultDebounce = time.Second
+
+// Config holds the settings shared by the CLI and both MCP servers
+type Config struct {
+	// TasksRoots are the directories holding one directory per task
+	TasksRoots []string
+	// Debounce is how long the watcher waits after the last write to a task
+	Debounce time.Duration
+	// Database is the cost database path, empty to disable it
+	Database string
+	// Options are the output location, writer and redaction settings
+	Options uilogparser.Options
+	// Budgets are the spend limits warned about after processing a task
+	Budgets Budgets
+}
+
+// fileConfig is the layout of the config file
+type fileConfig struct {
+	TasksRoots []string  `yaml:"tasks_roots"`
+	Debounce   *duration `yaml:"debounce"`
+	Database   *string   `yaml:"database"`
+	Source     string    `yaml:"source"`
+	Output     struct {
+		Location       string `yaml:"location"`
+		Directory      string `yaml:"directory"`
+		FileName       string `yaml:"file_name"`
+		GitIgnore      string `yaml:"git_ignore"`
+		WorkspaceRoots string `yaml:"workspace_roots"`
+		Reports        *bool  `yaml:"reports"`
+		UnchangedReads bool   `yaml:"unchanged_reads_only"`
+	} `yaml:"output"`
+	Writer struct {
+		Format        string   `yaml:"format"`
+		Profile       string   `yaml:"profile"`
+		Columns       []string `yaml:"columns"`
+		MaxTextLength int      `yaml:"max_text_length"`
+		TextMode      string   `yaml:"text_mode"`
+		TimeZone      string   `yaml:"timezone"`
+		Currency      string   `yaml:"currency"`
+		FXRates       string   `yaml:"fx_rates"`
+	} `yaml:"writer"`
+	Redaction struct {
+		Enabled  bool     `yaml:"enabled"`
+		Patterns []string `yaml:"patterns"`
+	} `yaml:"redaction"`
+	Anonymize struct {
+		Salt   string   `yaml:"salt"`
+		Bucket duration `yaml:"bucket"`
+	} `yaml:"anonymize"`
+	Budgets struct {
+		Task       float64 `yaml:"task"`
+		Repository float64 `yaml:"repository"`
+		Period     string  `yaml:"period"`
+	} `yaml:"budgets"`
+}
+
+// duration is a time.Duration written in the config file as a Go duration
+// such as "1h", which yaml.v3 does not parse into a time.Duration itself
+type duration time.Duration
+
+// UnmarshalYAML parses a Go duration string
+func (d *duration) UnmarshalYAML(value *yaml.Node) error {
+	var text string
+	if err := value.Decode(&text); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", value.Line, err)
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// DefaultPath returns the config file location, from COST_TRACKER_CONFIG or
+// $XDG_CONFIG_HOME/cline-cost-tracker/config.yaml (~/.config when unset)
+func DefaultPath() string {
+	if path := os.Getenv(PathEnvVar); path != "" {
+		return path
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "cline-cost-tracker", "config.yaml")
+}
+
+// Default returns the settings used when nothing is configured
+func Default() *Config {
+	return &Config{
+		TasksRoots: DefaultTasksRoots(),
+		Debounce:   DefaultDebounce,
+		Database:   store.DefaultPath(),
+		Budgets:    Budgets{Period: DefaultBudgetPeriod},
+	}
+}
+
+// DefaultTasksRoots returns the task directories used when none are
+// configured: every one of Cline, Roo Code and Kilo Code found on this machine
+func DefaultTasksRoots() []string {
+	var roots []string
+	for _, source := range uilogparser.Sources {
+		if source.ExtensionID != "" {
+			roots = append(roots, DiscoverTasksRoots(source.ExtensionID)...)
+		}
+	}
+	return roots
+}
+
+// Load reads the config file at path, DefaultPath when empty, and applies the
+// environment overrides. A missing file at the default path is not an error.
+func Load(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(expandHome(path))
+		switch {
+		case err == nil:
+			if err := cfg.apply(data); err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+			}
+		case !os.IsNotExist(err) || explicit:
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	cfg.expandPaths()
+	return cfg, nil
+}
+
+// apply overrides settings with those in a config file. Unknown keys are
+// rejected so typos do not silently fall back to defaults.
+func (c *Config) apply(data []byte) error {
+	var file fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && err != io.EOF {
+		return err
+	}
+
+	if len(file.TasksRoots) > 0 {
+		c.TasksRoots = file.TasksRoots
+	}
+	if file.Debounce != nil {
+		c.Debounce = time.Duration(*file.Debounce)
+	}
+	if file.Database != nil {
+		c.Database = *file.Database
+	}
+
+	c.Options = uilogparser.Options{
+		Format:             file.Writer.Format,
+		CSVProfile:         file.Writer.Profile,
+		CSVColumns:         file.Writer.Columns,
+		MaxTextLength:      file.Writer.MaxTextLength,
+		TextMode:           file.Writer.TextMode,
+		TimeZone:           file.Writer.TimeZone,
+		Currency:           file.Writer.Currency,
+		FXRatesPath:        file.Writer.FXRates,
+		Redact:             file.Redaction.Enabled,
+		RedactPatterns:     file.Redaction.Patterns,
+		AnonymizeSalt:      file.Anonymize.Salt,
+		AnonymizeBucket:    time.Duration(file.Anonymize.Bucket),
+		OutputDir:          file.Output.Directory,
+		OutputLocation:     file.Output.Location,
+		FileName:           file.Output.FileName,
+		GitIgnore:          file.Output.GitIgnore,
+		WorkspaceRoots:     file.Output.WorkspaceRoots,
+		SkipReports:        file.Output.Reports != nil && !*file.Output.Reports,
+		UnchangedReadsOnly: file.Output.UnchangedReads,
+		Source:             file.Source,
+	}
+
+	c.Budgets.Task = file.Budgets.Task
+	c.Budgets.Repository = file.Budgets.Repository
+	if file.Budgets.Period != "" {
+		c.Budgets.Period = file.Budgets.Period
+	}
+	return nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (c *Config) applyEnv() error {
+	if roots := os.Getenv(TasksRootsEnvVar); roots != "" {
+		c.TasksRoots = filepath.SplitList(roots)
+	}
+	if value := os.Getenv(DebounceEnvVar); value != "" {
+		debounce, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", DebounceEnvVar, err)
+		}
+		c.Debounce = debounce
+	}
+	if path := os.Getenv(store.PathEnvVar); path != "" {
+		c.Database = path
+	}
+
+	c.Options.ApplyEnv()
+
+	for name, budget := range map[string]*float64{
+		TaskBudgetEnvVar:       &c.Budgets.Task,
+		RepositoryBudgetEnvVar: &c.Budgets.Repository,
+	} {
+		if value := os.Getenv(name); value != "" {
+			amount, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*budget = amount
+		}
+	}
+	if period := os.Getenv(BudgetPeriodEnvVar); period != "" {
+		c.Budgets.Period = period
+	}
+	return nil
+}
+
+// expandPaths expands a leading ~ in configured paths
+func (c *Config) expandPaths() {
+	for i, root := range c.TasksRoots {
+		c.TasksRoots[i] = expandHome(root)
+	}
+	c.Database = expandHome(c.Database)
+	c.Options.OutputDir = expandHome(c.Options.OutputDir)
+	c.Options.FXRatesPath = expandHome(c.Options.FXRatesPath)
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// writeConfig writes a config file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv unsets the environment variables a test relies on, for the
+// duration of the test
+func clearEnv(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadDurations(t *testing.T) {
+	clearEnv(t, DebounceEnvVar, uilogparser.AnonymizeBucketEnvVar)
+	cfg, err := Load(writeConfig(t, "debounce: 250ms\nanonymize:\n  salt: s\n  bucket: 1h30m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Debounce != 250*time.Millisecond {
+		t.Errorf("Debounce = %s, want 250ms", cfg.Debounce)
+	}
+	if cfg.Options.AnonymizeBucket != 90*time.Minute {
+		t.Errorf("AnonymizeBucket = %s, want 1h30m", cfg.Options.AnonymizeBucket)
+	}
+
+	if _, err := Load(writeConfig(t, "anonymize:\n  bucket: hourly\n")); err == nil {
+		t.Error("invalid bucket accepted")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t, TaskBudgetEnvVar, RepositoryBudgetEnvVar, uilogparser.OutputLocationEnvVar, uilogparser.CurrencyEnvVar)
+	path := writeConfig(t, `debounce: 2s
+output:
+  location: central
+writer:
+  currency: EUR
+budgets:
+  task: 1
+  repository: 10
+`)
+	t.Setenv(DebounceEnvVar, "3s")
+	t.Setenv(TaskBudgetEnvVar, "2")
+	t.Setenv(uilogparser.CurrencyEnvVar, "AUD")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-config", path, "-debounce", "4s", "-currency", "GBP"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"debounce from flag over env and file", cfg.Debounce, 4 * time.Second},
+		{"currency from flag over env and file", cfg.Options.Currency, "GBP"},
+		{"task budget from env over file", cfg.Budgets.Task, 2.0},
+		{"repository budget from file", cfg.Budgets.Repository, 10.0},
+		{"location from file", cfg.Options.OutputLocation, uilogparser.OutputLocationCentral},
+		{"budget period from default", cfg.Budgets.Period, DefaultBudgetPeriod},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadReports(t *testing.T) {
+	clearEnv(t, uilogparser.ReportsEnvVar)
+	tests := []struct {
+		content string
+		skip    bool
+	}{
+		{"", false},
+		{"output:\n  reports: true\n", false},
+		{"output:\n  reports: false\n", true},
+	}
+	for _, tt := range tests {
+		cfg, err := Load(writeConfig(t, tt.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Options.SkipReports != tt.skip {
+			t.Errorf("%q: SkipReports = %v, want %v", tt.content, cfg.Options.SkipReports, tt.skip)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	_, err := Load(writeConfig(t, "output:\n  locaton: central\n"))
+	if err == nil || !strings.Contains(err.Error(), "locaton") {
+		t.Errorf("Load error = %v, want one naming the unknown key", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "config.yaml")
+	if _, err := Load(missing); err == nil {
+		t.Error("missing explicit config file accepted")
+	}
+	t.Setenv(PathEnvVar, missing)
+	if _, err := Load(""); err != nil {
+		t.Errorf("missing default config file: %v", err)
+	}
+}
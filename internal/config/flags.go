@@ -0,0 +1,100 @@
+package config
+
+import (
+	"flag"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mcbadger88/cline-task-cost-tracker/internal/store"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// PathFromArgs returns the value of the -config flag in args, empty when it is
+// not given. The config file has to be loaded before the other flags are
+// registered, since its settings become their defaults.
+func PathFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if value, ok := strings.CutPrefix(name, "config="); ok {
+			return value
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// RegisterFlags registers flags overriding every setting on fs, defaulting to
+// the loaded values
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "config file (defaults to $"+PathEnvVar+", or ~/.config/cline-cost-tracker/config.yaml)")
+	fs.Var(&listFlag{list: &c.TasksRoots, split: filepath.SplitList}, "tasks-root", "tasks directory to watch, repeatable (defaults to $"+TasksRootsEnvVar+")")
+	fs.DurationVar(&c.Debounce, "debounce", c.Debounce, "wait after the last write to a task before processing it (defaults to $"+DebounceEnvVar+", or 1s)")
+	fs.StringVar(&c.Database, "db", c.Database, "cost database path, empty to disable (defaults to $"+store.PathEnvVar+")")
+	fs.StringVar(&c.Options.Source, "source", c.Options.Source, "agent that wrote the tasks: cline, roo-code, kilo-code or claude-code, detected from the task path when empty (defaults to $"+uilogparser.SourceEnvVar+")")
+	fs.StringVar(&c.Options.OutputLocation, "output-location", c.Options.OutputLocation, "where output is written: repo, central or data (defaults to $"+uilogparser.OutputLocationEnvVar+", or repo)")
+	fs.StringVar(&c.Options.OutputDir, "output-dir", c.Options.OutputDir, "output directory; for the repo location relative to the task's working directory unless absolute, for the central location the absolute directory partitioned by repository (defaults to $"+uilogparser.OutputDirEnvVar+", or "+uilogparser.DefaultOutputDir+")")
+	fs.StringVar(&c.Options.FileName, "file-name", c.Options.FileName, "output file name template using {task_id}, {started}, {date}, {repository} and {source} (defaults to $"+uilogparser.FileNameEnvVar+", or "+uilogparser.DefaultFileName+")")
+	fs.StringVar(&c.Options.GitIgnore, "git-ignore", c.Options.GitIgnore, "ignore the logs directory when first creating it in a git repository: off, exclude (.git/info/exclude) or gitignore (defaults to $"+uilogparser.GitIgnoreEnvVar+", or off)")
+	fs.StringVar(&c.Options.WorkspaceRoots, "workspace-roots", c.Options.WorkspaceRoots, "roots of a multi-root workspace receiving the output: primary or each (defaults to $"+uilogparser.WorkspaceRootsEnvVar+", or primary)")
+	fs.BoolVar(&c.Options.UnchangedReadsOnly, "unchanged-reads-only", c.Options.UnchangedReadsOnly, "list only re-reads of files unchanged since the previous read as redundant in reports (defaults to $"+uilogparser.UnchangedReadsEnvVar+")")
+	fs.BoolFunc("reports", "write the text, Markdown and HTML reports of each task next to its records, -reports=false to skip them (defaults to $"+uilogparser.ReportsEnvVar+", or true)", func(value string) error {
+		reports, err := strconv.ParseBool(value)
+		c.Options.SkipReports = !reports
+		return err
+	})
+
+	fs.StringVar(&c.Options.Format, "format", c.Options.Format, "output format: csv, jsonl, bundle or anonymized (defaults to $"+uilogparser.FormatEnvVar+", or csv)")
+	fs.StringVar(&c.Options.CSVProfile, "profile", c.Options.CSVProfile, "CSV column profile: minimal, standard or forensic (defaults to $"+uilogparser.ProfileEnvVar+", or standard)")
+	fs.Var(&listFlag{list: &c.Options.CSVColumns, split: uilogparser.ParseColumnList}, "columns", "comma separated CSV columns, overriding the profile (defaults to $"+uilogparser.ColumnsEnvVar+")")
+	fs.IntVar(&c.Options.MaxTextLength, "text-max", c.Options.MaxTextLength, "truncate the CSV Text column to this many bytes, 0 for the profile's limit (defaults to $"+uilogparser.MaxTextLengthEnvVar+")")
+	fs.StringVar(&c.Options.TextMode, "text-mode", c.Options.TextMode, "CSV Text column: full, omit or hash (defaults to $"+uilogparser.TextModeEnvVar+", or the profile's mode)")
+	fs.StringVar(&c.Options.TimeZone, "timezone", c.Options.TimeZone, "zone of timestamps: utc, local, task or an IANA name (defaults to $"+uilogparser.TimeZoneEnvVar+", or local)")
+	fs.StringVar(&c.Options.Currency, "currency", c.Options.Currency, "display currency such as AUD, converted at each request's date (defaults to $"+uilogparser.CurrencyEnvVar+", or USD)")
+	fs.StringVar(&c.Options.FXRatesPath, "fx-rates", c.Options.FXRatesPath, "CSV file of date,currency,rate exchange rates from USD (defaults to $"+uilogparser.FXRatesEnvVar+")")
+	fs.BoolVar(&c.Options.Redact, "redact", c.Options.Redact, "mask secrets, emails and home directory usernames before writing (defaults to $"+uilogparser.RedactEnvVar+")")
+	fs.Var(&listFlag{list: &c.Options.RedactPatterns, split: singleValue}, "redact-pattern", "regular expression to mask in addition to the built-in rules, repeatable (defaults to $"+uilogparser.RedactPatternsEnvVar+")")
+
+	fs.Float64Var(&c.Budgets.Task, "task-budget", c.Budgets.Task, "warn when a task costs more than this many USD, 0 for no limit (defaults to $"+TaskBudgetEnvVar+")")
+	fs.Float64Var(&c.Budgets.Repository, "repository-budget", c.Budgets.Repository, "warn when a repository spends more than this many USD over the budget period, 0 for no limit (defaults to $"+RepositoryBudgetEnvVar+")")
+	fs.StringVar(&c.Budgets.Period, "budget-period", c.Budgets.Period, "repository budget window, e.g. 30d (defaults to $"+BudgetPeriodEnvVar+", or "+DefaultBudgetPeriod+")")
+}
+
+// listFlag is a repeatable flag holding a list. The first use replaces the
+// configured list and later uses append to it.
+type listFlag struct {
+	list  *[]string
+	split func(string) []string
+	set   bool
+}
+
+// String returns the list as the flag package shows defaults
+func (f *listFlag) String() string {
+	if f.list == nil {
+		return ""
+	}
+	return strings.Join(*f.list, ",")
+}
+
+// Set adds the values in one use of the flag
+func (f *listFlag) Set(value string) error {
+	if !f.set {
+		*f.list = nil
+		f.set = true
+	}
+	*f.list = append(*f.list, f.split(value)...)
+	return nil
+}
+
+// singleValue splits nothing, for flags whose values may contain any separator
+func singleValue(value string) []string {
+	return []string{value}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// editorDirs are the user data directory names of VS Code and the forks
+// Cline runs in, under the platform's user config directory
+var editorDirs = []string{"Code", "Code - Insiders", "VSCodium", "Cursor", "Windsurf"}
+
+// remoteServerDirs are the data directories the editors' remote servers
+// (Remote-SSH, WSL, dev containers) keep under the home directory
+var remoteServerDirs = []string{".vscode-server", ".vscode-server-insiders", ".vscodium-server", ".cursor-server", ".windsurf-server"}
+
+// CandidateTasksRoots returns every directory an extension may keep its tasks
+// in on this machine: the global storage of each desktop editor and of each
+// remote server
+func CandidateTasksRoots(extensionID string) []string {
+	var roots []string
+	// ~/Library/Application Support on macOS, $XDG_CONFIG_HOME or ~/.config
+	// on Linux and %AppData% on Windows
+	if configDir, err := os.UserConfigDir(); err == nil {
+		for _, editor := range editorDirs {
+			roots = append(roots, filepath.Join(configDir, editor, "User", "globalStorage", extensionID, "tasks"))
+		}
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		for _, server := range remoteServerDirs {
+			roots = append(roots, filepath.Join(homeDir, server, "data", "User", "globalStorage", extensionID, "tasks"))
+		}
+	}
+	return roots
+}
+
+// CandidateAgentTasksRoots returns the candidate task directories of every
+// editor extension in uilogparser.Sources
+func CandidateAgentTasksRoots() []string {
+	var roots []string
+	for _, source := range uilogparser.Sources {
+		if source.ExtensionID != "" {
+			roots = append(roots, CandidateTasksRoots(source.ExtensionID)...)
+		}
+	}
+	return roots
+}
+
+// DiscoverTasksRoots returns the candidate task directories of an extension
+// that exist
+func DiscoverTasksRoots(extensionID string) []string {
+	var roots []string
+	for _, root := range CandidateTasksRoots(extensionID) {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,550 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// PathEnvVar is the environment variable that overrides the database location
+const PathEnvVar = "COST_TRACKER_DB"
+
+// schema creates the tables of the cost store. Timestamps are Unix milliseconds.
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	task_id           TEXT PRIMARY KEY,
+	source            TEXT NOT NULL,
+	repository        TEXT NOT NULL,
+	working_directory TEXT NOT NULL,
+	cost_centre       TEXT NOT NULL DEFAULT '',
+	repository_root   TEXT NOT NULL DEFAULT '',
+	subdirectory      TEXT NOT NULL DEFAULT '',
+	workspace_roots   TEXT NOT NULL DEFAULT '',
+	start_branch      TEXT NOT NULL DEFAULT '',
+	start_commit      TEXT NOT NULL DEFAULT '',
+	end_branch        TEXT NOT NULL DEFAULT '',
+	end_commit        TEXT NOT NULL DEFAULT '',
+	prompt            TEXT NOT NULL,
+	started_at        INTEGER NOT NULL,
+	ended_at          INTEGER NOT NULL,
+	total_cost        REAL NOT NULL,
+	tokens_in         INTEGER NOT NULL,
+	tokens_out        INTEGER NOT NULL,
+	cache_writes      INTEGER NOT NULL,
+	cache_reads       INTEGER NOT NULL,
+	api_requests      INTEGER NOT NULL,
+	tool_calls        INTEGER NOT NULL,
+	messages          INTEGER NOT NULL,
+	redacted          INTEGER NOT NULL DEFAULT 0,
+	redactions        TEXT NOT NULL DEFAULT '',
+	updated_at        INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_requests (
+	id             TEXT PRIMARY KEY,
+	task_id        TEXT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+	message_index  INTEGER NOT NULL,
+	timestamp      INTEGER NOT NULL,
+	tokens_in      INTEGER NOT NULL,
+	tokens_out     INTEGER NOT NULL,
+	cache_writes   INTEGER NOT NULL,
+	cache_reads    INTEGER NOT NULL,
+	context_tokens INTEGER NOT NULL,
+	cost           REAL NOT NULL,
+	cancel_reason  TEXT NOT NULL,
+	branch         TEXT NOT NULL DEFAULT '',
+	commit_hash    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS api_requests_task_id ON api_requests(task_id);
+CREATE INDEX IF NOT EXISTS api_requests_timestamp ON api_requests(timestamp);
+
+CREATE TABLE IF NOT EXISTS tool_calls (
+	id            TEXT PRIMARY KEY,
+	task_id       TEXT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+	request_id    TEXT NOT NULL,
+	message_index INTEGER NOT NULL,
+	timestamp     INTEGER NOT NULL,
+	tool          TEXT NOT NULL,
+	path          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tool_calls_task_id ON tool_calls(task_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id            TEXT PRIMARY KEY,
+	task_id       TEXT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+	message_index INTEGER NOT NULL,
+	timestamp     INTEGER NOT NULL,
+	type          TEXT NOT NULL,
+	kind          TEXT NOT NULL,
+	text          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS messages_task_id ON messages(task_id);
+
+CREATE TABLE IF NOT EXISTS commits (
+	id           TEXT PRIMARY KEY,
+	task_id      TEXT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+	hash         TEXT NOT NULL,
+	branch       TEXT NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	subject      TEXT NOT NULL,
+	api_requests INTEGER NOT NULL,
+	cost         REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS commits_task_id ON commits(task_id);
+`
+
+// columns are added to tables of databases created by earlier versions
+var columns = []struct {
+	table, name, definition string
+}{
+	{"tasks", "cost_centre", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "repository_root", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "subdirectory", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "workspace_roots", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "start_branch", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "start_commit", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "end_branch", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "end_commit", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "redacted", "INTEGER NOT NULL DEFAULT 0"},
+	{"tasks", "redactions", "TEXT NOT NULL DEFAULT ''"},
+	{"api_requests", "branch", "TEXT NOT NULL DEFAULT ''"},
+	{"api_requests", "commit_hash", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// Store is a SQLite database holding the ledgers of every processed task
+type Store struct {
+	db *sql.DB
+}
+
+// RepositorySpend is the cost of API requests made in one repository
+type RepositorySpend struct {
+	Repository  string
+	Tasks       int
+	APIRequests int
+	Cost        float64
+}
+
+// BranchSpend is the cost of API requests made on one branch of a repository
+type BranchSpend struct {
+	Repository  string
+	Branch      string
+	Tasks       int
+	APIRequests int
+	Cost        float64
+}
+
+// CommitSpend is the cost of the API requests a commit shipped, summed over
+// the tasks it was created during
+type CommitSpend struct {
+	Repository  string
+	Hash        string
+	Branch      string
+	Subject     string
+	Timestamp   int64
+	APIRequests int
+	Cost        float64
+}
+
+// DefaultPath returns the database location, from COST_TRACKER_DB or under
+// $XDG_DATA_HOME/cline-cost-tracker (~/.local/share when unset)
+func DefaultPath() string {
+	if path := os.Getenv(PathEnvVar); path != "" {
+		return path
+	}
+	dataDir := uilogparser.DataDirectory()
+	if dataDir == "" {
+		return "costs.db"
+	}
+	return filepath.Join(dataDir, "costs.db")
+}
+
+// Open opens the database at path, creating it and its tables if needed
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// The watcher processes tasks from timer goroutines, serialize writes
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %v", err)
+	}
+	if err := addColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// addColumns adds the columns missing from databases created by earlier
+// versions
+func addColumns(db *sql.DB) error {
+	for _, column := range columns {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", column.table, column.name).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %v", column.table, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE " + column.table + " ADD COLUMN " + column.name + " " + column.definition); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %v", column.table, column.name, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// UpsertLedger replaces everything stored for the ledger's task
+func (s *Store) UpsertLedger(ledger *uilogparser.Ledger) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// The start of a task is read from the reflog when there is one, else from
+	// HEAD when the task is processed, so the first start stored is kept
+	task := ledger.Task
+	_, err = tx.Exec(`INSERT INTO tasks (task_id, source, repository, working_directory, cost_centre, repository_root, subdirectory, workspace_roots,
+		start_branch, start_commit, end_branch, end_commit, prompt, started_at, ended_at,
+		total_cost, tokens_in, tokens_out, cache_writes, cache_reads, api_requests, tool_calls, messages, redacted, redactions, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(task_id) DO UPDATE SET
+			source = excluded.source, repository = excluded.repository, working_directory = excluded.working_directory,
+			cost_centre = excluded.cost_centre, repository_root = excluded.repository_root, subdirectory = excluded.subdirectory, workspace_roots = excluded.workspace_roots,
+			start_branch = CASE WHEN tasks.start_commit = '' AND tasks.start_branch = '' THEN excluded.start_branch ELSE tasks.start_branch END,
+			start_commit = CASE WHEN tasks.start_commit = '' AND tasks.start_branch = '' THEN excluded.start_commit ELSE tasks.start_commit END,
+			end_branch = excluded.end_branch, end_commit = excluded.end_commit,
+			prompt = excluded.prompt, started_at = excluded.started_at, ended_at = excluded.ended_at,
+			total_cost = excluded.total_cost, tokens_in = excluded.tokens_in, tokens_out = excluded.tokens_out,
+			cache_writes = excluded.cache_writes, cache_reads = excluded.cache_reads, api_requests = excluded.api_requests,
+			tool_calls = excluded.tool_calls, messages = excluded.messages,
+			redacted = excluded.redacted, redactions = excluded.redactions, updated_at = excluded.updated_at`,
+		task.TaskID, task.Source, task.Repository, task.WorkingDirectory, task.CostCentre, task.RepositoryRoot, task.Subdirectory, joinLines(task.WorkspaceRoots),
+		task.StartBranch, task.StartCommit, task.EndBranch, task.EndCommit, task.Prompt, task.StartedAt, task.EndedAt,
+		task.TotalCost, task.TokensIn, task.TokensOut, task.CacheWrites, task.CacheReads, task.APIRequests,
+		task.ToolCalls, task.Messages, task.Redacted, task.Redactions, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to upsert task: %v", err)
+	}
+
+	// Cline rewrites ui_messages.json as a whole and can drop messages, so
+	// child rows are replaced rather than merged
+	for _, table := range []string{"api_requests", "tool_calls", "messages", "commits"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE task_id = ?", task.TaskID); err != nil {
+			return fmt.Errorf("failed to clear %s: %v", table, err)
+		}
+	}
+
+	for _, req := range ledger.APIRequests {
+		_, err := tx.Exec(`INSERT INTO api_requests (id, task_id, message_index, timestamp, tokens_in, tokens_out,
+			cache_writes, cache_reads, context_tokens, cost, cancel_reason, branch, commit_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			req.ID, req.TaskID, req.MessageIndex, req.Timestamp, req.TokensIn, req.TokensOut,
+			req.CacheWrites, req.CacheReads, req.ContextTokens, req.Cost, req.CancelReason, req.Branch, req.Commit)
+		if err != nil {
+			return fmt.Errorf("failed to insert API request: %v", err)
+		}
+	}
+
+	for _, call := range ledger.ToolCalls {
+		_, err := tx.Exec(`INSERT INTO tool_calls (id, task_id, request_id, message_index, timestamp, tool, path)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			call.ID, call.TaskID, call.RequestID, call.MessageIndex, call.Timestamp, call.Tool, call.Path)
+		if err != nil {
+			return fmt.Errorf("failed to insert tool call: %v", err)
+		}
+	}
+
+	for _, msg := range ledger.Messages {
+		_, err := tx.Exec(`INSERT INTO messages (id, task_id, message_index, timestamp, type, kind, text)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			msg.ID, msg.TaskID, msg.MessageIndex, msg.Timestamp, msg.Type, msg.Kind, msg.Text)
+		if err != nil {
+			return fmt.Errorf("failed to insert message: %v", err)
+		}
+	}
+
+	for _, commit := range ledger.Commits {
+		_, err := tx.Exec(`INSERT INTO commits (id, task_id, hash, branch, timestamp, subject, api_requests, cost)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			commit.ID, commit.TaskID, commit.Hash, commit.Branch, commit.Timestamp, commit.Subject, commit.Requests, commit.Cost)
+		if err != nil {
+			return fmt.Errorf("failed to insert commit: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// SpendByRepository returns the cost of API requests made since the given
+// time, grouped by repository and ordered by cost
+func (s *Store) SpendByRepository(since time.Time) ([]RepositorySpend, error) {
+	rows, err := s.db.Query(`SELECT t.repository, COUNT(DISTINCT t.task_id), COUNT(r.id), COALESCE(SUM(r.cost), 0)
+		FROM api_requests r JOIN tasks t ON t.task_id = r.task_id
+		WHERE r.timestamp >= ?
+		GROUP BY t.repository
+		ORDER BY SUM(r.cost) DESC`, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spend: %v", err)
+	}
+	defer rows.Close()
+
+	var spend []RepositorySpend
+	for rows.Next() {
+		var r RepositorySpend
+		if err := rows.Scan(&r.Repository, &r.Tasks, &r.APIRequests, &r.Cost); err != nil {
+			return nil, fmt.Errorf("failed to read spend: %v", err)
+		}
+		spend = append(spend, r)
+	}
+	return spend, rows.Err()
+}
+
+// SpendByBranch returns the cost of API requests made since the given time
+// in tasks that worked in git, grouped by repository and the branch checked
+// out, ordered by cost
+func (s *Store) SpendByBranch(since time.Time) ([]BranchSpend, error) {
+	rows, err := s.db.Query(`SELECT t.repository, r.branch, COUNT(DISTINCT t.task_id), COUNT(r.id), COALESCE(SUM(r.cost), 0)
+		FROM api_requests r JOIN tasks t ON t.task_id = r.task_id
+		WHERE r.timestamp >= ? AND (t.end_branch != '' OR t.end_commit != '')
+		GROUP BY t.repository, r.branch
+		ORDER BY SUM(r.cost) DESC`, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spend: %v", err)
+	}
+	defer rows.Close()
+
+	var spend []BranchSpend
+	for rows.Next() {
+		var b BranchSpend
+		if err := rows.Scan(&b.Repository, &b.Branch, &b.Tasks, &b.APIRequests, &b.Cost); err != nil {
+			return nil, fmt.Errorf("failed to read spend: %v", err)
+		}
+		spend = append(spend, b)
+	}
+	return spend, rows.Err()
+}
+
+// SpendByCommit returns the cost of the API requests shipped by each commit
+// created since the given time, most expensive first, at most limit commits
+func (s *Store) SpendByCommit(since time.Time, limit int) ([]CommitSpend, error) {
+	rows, err := s.db.Query(`SELECT t.repository, c.hash, MIN(c.branch), MIN(c.subject), MIN(c.timestamp), SUM(c.api_requests), SUM(c.cost)
+		FROM commits c JOIN tasks t ON t.task_id = c.task_id
+		WHERE c.timestamp >= ?
+		GROUP BY t.repository, c.hash
+		ORDER BY SUM(c.cost) DESC
+		LIMIT ?`, since.UnixMilli(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spend: %v", err)
+	}
+	defer rows.Close()
+
+	var spend []CommitSpend
+	for rows.Next() {
+		var c CommitSpend
+		if err := rows.Scan(&c.Repository, &c.Hash, &c.Branch, &c.Subject, &c.Timestamp, &c.APIRequests, &c.Cost); err != nil {
+			return nil, fmt.Errorf("failed to read spend: %v", err)
+		}
+		spend = append(spend, c)
+	}
+	return spend, rows.Err()
+}
+
+// RepositoryCost returns the cost of API requests made in a repository since
+// the given time
+func (s *Store) RepositoryCost(repository string, since time.Time) (float64, error) {
+	var cost float64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(r.cost), 0)
+		FROM api_requests r JOIN tasks t ON t.task_id = r.task_id
+		WHERE t.repository = ? AND r.timestamp >= ?`, repository, since.UnixMilli()).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query spend: %v", err)
+	}
+	return cost, nil
+}
+
+// Tasks returns the tasks active since the given time, most recent first
+func (s *Store) Tasks(since time.Time) ([]uilogparser.LedgerTask, error) {
+	rows, err := s.db.Query(`SELECT task_id, source, repository, working_directory, cost_centre, repository_root, subdirectory, workspace_roots,
+		start_branch, start_commit, end_branch, end_commit, prompt, started_at, ended_at,
+		total_cost, tokens_in, tokens_out, cache_writes, cache_reads, api_requests, tool_calls, messages, redacted, redactions
+		FROM tasks WHERE ended_at >= ? ORDER BY ended_at DESC`, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []uilogparser.LedgerTask
+	for rows.Next() {
+		var t uilogparser.LedgerTask
+		var workspaceRoots string
+		if err := rows.Scan(&t.TaskID, &t.Source, &t.Repository, &t.WorkingDirectory, &t.CostCentre, &t.RepositoryRoot, &t.Subdirectory, &workspaceRoots,
+			&t.StartBranch, &t.StartCommit, &t.EndBranch, &t.EndCommit, &t.Prompt, &t.StartedAt, &t.EndedAt,
+			&t.TotalCost, &t.TokensIn, &t.TokensOut, &t.CacheWrites, &t.CacheReads, &t.APIRequests, &t.ToolCalls, &t.Messages, &t.Redacted, &t.Redactions); err != nil {
+			return nil, fmt.Errorf("failed to read task: %v", err)
+		}
+		t.WorkspaceRoots = splitLines(workspaceRoots)
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// RepositoryLedgers loads the full ledger of every task in a repository,
+// oldest first
+func (s *Store) RepositoryLedgers(repository string) ([]*uilogparser.Ledger, error) {
+	return s.queryLedgers("repository = ?", repository)
+}
+
+// Ledgers loads the full ledger of every task active since the given time,
+// oldest first
+func (s *Store) Ledgers(since time.Time) ([]*uilogparser.Ledger, error) {
+	return s.queryLedgers("ended_at >= ?", since.UnixMilli())
+}
+
+// queryLedgers loads the full ledgers of the tasks matching a WHERE clause
+func (s *Store) queryLedgers(where string, args ...interface{}) ([]*uilogparser.Ledger, error) {
+	rows, err := s.db.Query(`SELECT task_id, source, repository, working_directory, cost_centre, repository_root, subdirectory, workspace_roots,
+		start_branch, start_commit, end_branch, end_commit, prompt, started_at, ended_at,
+		total_cost, tokens_in, tokens_out, cache_writes, cache_reads, api_requests, tool_calls, messages, redacted, redactions
+		FROM tasks WHERE `+where+` ORDER BY started_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %v", err)
+	}
+
+	var ledgers []*uilogparser.Ledger
+	for rows.Next() {
+		ledger := &uilogparser.Ledger{}
+		t := &ledger.Task
+		var workspaceRoots string
+		if err := rows.Scan(&t.TaskID, &t.Source, &t.Repository, &t.WorkingDirectory, &t.CostCentre, &t.RepositoryRoot, &t.Subdirectory, &workspaceRoots,
+			&t.StartBranch, &t.StartCommit, &t.EndBranch, &t.EndCommit, &t.Prompt, &t.StartedAt, &t.EndedAt,
+			&t.TotalCost, &t.TokensIn, &t.TokensOut, &t.CacheWrites, &t.CacheReads, &t.APIRequests, &t.ToolCalls, &t.Messages, &t.Redacted, &t.Redactions); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read task: %v", err)
+		}
+		t.WorkspaceRoots = splitLines(workspaceRoots)
+		ledgers = append(ledgers, ledger)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, ledger := range ledgers {
+		if err := s.loadLedgerRows(ledger); err != nil {
+			return nil, err
+		}
+	}
+	return ledgers, nil
+}
+
+// loadLedgerRows fills in the API requests, tool calls, messages and commits
+// of a ledger whose task has already been read
+func (s *Store) loadLedgerRows(ledger *uilogparser.Ledger) error {
+	taskID := ledger.Task.TaskID
+
+	rows, err := s.db.Query(`SELECT id, task_id, message_index, timestamp, tokens_in, tokens_out, cache_writes,
+		cache_reads, context_tokens, cost, cancel_reason, branch, commit_hash FROM api_requests WHERE task_id = ? ORDER BY message_index`, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to query API requests: %v", err)
+	}
+	for rows.Next() {
+		var r uilogparser.LedgerAPIRequest
+		if err := rows.Scan(&r.ID, &r.TaskID, &r.MessageIndex, &r.Timestamp, &r.TokensIn, &r.TokensOut, &r.CacheWrites,
+			&r.CacheReads, &r.ContextTokens, &r.Cost, &r.CancelReason, &r.Branch, &r.Commit); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read API request: %v", err)
+		}
+		ledger.APIRequests = append(ledger.APIRequests, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read API requests: %v", err)
+	}
+
+	rows, err = s.db.Query(`SELECT id, task_id, request_id, message_index, timestamp, tool, path
+		FROM tool_calls WHERE task_id = ? ORDER BY message_index`, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to query tool calls: %v", err)
+	}
+	for rows.Next() {
+		var c uilogparser.LedgerToolCall
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.RequestID, &c.MessageIndex, &c.Timestamp, &c.Tool, &c.Path); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read tool call: %v", err)
+		}
+		ledger.ToolCalls = append(ledger.ToolCalls, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read tool calls: %v", err)
+	}
+
+	rows, err = s.db.Query(`SELECT id, task_id, message_index, timestamp, type, kind, text
+		FROM messages WHERE task_id = ? ORDER BY message_index`, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to query messages: %v", err)
+	}
+	for rows.Next() {
+		var m uilogparser.LedgerMessage
+		if err := rows.Scan(&m.ID, &m.TaskID, &m.MessageIndex, &m.Timestamp, &m.Type, &m.Kind, &m.Text); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read message: %v", err)
+		}
+		ledger.Messages = append(ledger.Messages, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read messages: %v", err)
+	}
+
+	rows, err = s.db.Query(`SELECT id, task_id, hash, branch, timestamp, subject, api_requests, cost
+		FROM commits WHERE task_id = ? ORDER BY timestamp`, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to query commits: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c uilogparser.LedgerCommit
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.Hash, &c.Branch, &c.Timestamp, &c.Subject, &c.Requests, &c.Cost); err != nil {
+			return fmt.Errorf("failed to read commit: %v", err)
+		}
+		ledger.Commits = append(ledger.Commits, c)
+	}
+	return rows.Err()
+}
+
+// joinLines stores a list as one value per line
+func joinLines(values []string) string {
+	return strings.Join(values, "\n")
+}
+
+// splitLines reads a list stored by joinLines
+func splitLines(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
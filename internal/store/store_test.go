@@ -0,0 +1,175 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// openTest opens a store in a temporary directory
+func openTest(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "costs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// testLedger returns the ledger of a task in repository with one API request
+// per cost, made a minute apart from start
+func testLedger(taskID, repository string, start time.Time, costs ...float64) *uilogparser.Ledger {
+	ledger := &uilogparser.Ledger{Task: uilogparser.LedgerTask{
+		TaskID:           taskID,
+		Source:           uilogparser.SourceCline,
+		Repository:       repository,
+		WorkingDirectory: "/src/" + repository,
+		RepositoryRoot:   "/src/" + repository,
+		WorkspaceRoots:   []string{"/src/" + repository, "/src/docs"},
+		StartBranch:      "main",
+		StartCommit:      "aaaa",
+		EndBranch:        "main",
+		EndCommit:        "bbbb",
+		Prompt:           "fix the parser",
+		StartedAt:        start.UnixMilli(),
+		EndedAt:          start.Add(time.Duration(len(costs)) * time.Minute).UnixMilli(),
+		APIRequests:      len(costs),
+		ToolCalls:        1,
+		Messages:         len(costs) + 1,
+		Redacted:         true,
+		Redactions:       "email:1",
+	}}
+	for i, cost := range costs {
+		index := i * 2
+		ledger.APIRequests = append(ledger.APIRequests, uilogparser.LedgerAPIRequest{
+			ID: uilogparser.LedgerID(taskID, index), TaskID: taskID, MessageIndex: index,
+			Timestamp: start.Add(time.Duration(i) * time.Minute).UnixMilli(),
+			TokensIn:  100, TokensOut: 10, CacheWrites: 5, CacheReads: 50, ContextTokens: 165,
+			Cost: cost, Branch: "main", Commit: "bbbb",
+		})
+		ledger.Task.TotalCost += cost
+		ledger.Messages = append(ledger.Messages, uilogparser.LedgerMessage{
+			ID: uilogparser.LedgerID(taskID, index), TaskID: taskID, MessageIndex: index,
+			Timestamp: start.Add(time.Duration(i) * time.Minute).UnixMilli(), Type: "say", Kind: "api_req_started", Text: "{}",
+		})
+	}
+	ledger.ToolCalls = []uilogparser.LedgerToolCall{{
+		ID: uilogparser.LedgerID(taskID, 1), TaskID: taskID, RequestID: uilogparser.LedgerID(taskID, 0),
+		MessageIndex: 1, Timestamp: start.UnixMilli(), Tool: "read_file", Path: "main.go",
+	}}
+	ledger.Commits = []uilogparser.LedgerCommit{{
+		ID: taskID + "-bbbb", TaskID: taskID, Hash: "bbbb", Branch: "main",
+		Timestamp: ledger.Task.EndedAt, Subject: "fix parser", Requests: len(costs), Cost: ledger.Task.TotalCost,
+	}}
+	return ledger
+}
+
+func TestUpsertLedgerRoundTrip(t *testing.T) {
+	s := openTest(t)
+	start := time.Date(2025, 7, 28, 9, 0, 0, 0, time.UTC)
+	want := testLedger("1", "tracker", start, 0.5, 0.25)
+	if err := s.UpsertLedger(want); err != nil {
+		t.Fatal(err)
+	}
+	ledgers, err := s.RepositoryLedgers("tracker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledgers) != 1 || !reflect.DeepEqual(ledgers[0], want) {
+		t.Fatalf("read back %+v, want %+v", ledgers, want)
+	}
+
+	// Upserting again replaces the child rows, dropping those no longer in
+	// the task, and keeps the first start recorded
+	updated := testLedger("1", "tracker", start, 0.5)
+	updated.Task.StartBranch, updated.Task.StartCommit = "feature", "cccc"
+	if err := s.UpsertLedger(updated); err != nil {
+		t.Fatal(err)
+	}
+	ledgers, err = s.Ledgers(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledgers) != 1 {
+		t.Fatalf("got %d ledgers, want 1", len(ledgers))
+	}
+	got := ledgers[0]
+	if len(got.APIRequests) != 1 || len(got.Messages) != 1 || got.Task.TotalCost != 0.5 {
+		t.Errorf("upsert did not replace the task's rows: %+v", got)
+	}
+	if got.Task.StartBranch != "main" || got.Task.StartCommit != "aaaa" {
+		t.Errorf("start = %s %s, want the first recorded main aaaa", got.Task.StartBranch, got.Task.StartCommit)
+	}
+
+	tasks, err := s.Tasks(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || !reflect.DeepEqual(tasks[0], got.Task) {
+		t.Errorf("Tasks() = %+v, want %+v", tasks, got.Task)
+	}
+}
+
+func TestOpenMigratesOldDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "costs.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tables as the first version of the store created them
+	_, err = db.Exec(`
+CREATE TABLE tasks (
+	task_id TEXT PRIMARY KEY, source TEXT NOT NULL, repository TEXT NOT NULL, working_directory TEXT NOT NULL,
+	prompt TEXT NOT NULL, started_at INTEGER NOT NULL, ended_at INTEGER NOT NULL, total_cost REAL NOT NULL,
+	tokens_in INTEGER NOT NULL, tokens_out INTEGER NOT NULL, cache_writes INTEGER NOT NULL, cache_reads INTEGER NOT NULL,
+	api_requests INTEGER NOT NULL, tool_calls INTEGER NOT NULL, messages INTEGER NOT NULL, updated_at INTEGER NOT NULL
+);
+CREATE TABLE api_requests (
+	id TEXT PRIMARY KEY, task_id TEXT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+	message_index INTEGER NOT NULL, timestamp INTEGER NOT NULL, tokens_in INTEGER NOT NULL, tokens_out INTEGER NOT NULL,
+	cache_writes INTEGER NOT NULL, cache_reads INTEGER NOT NULL, context_tokens INTEGER NOT NULL,
+	cost REAL NOT NULL, cancel_reason TEXT NOT NULL
+);
+INSERT INTO tasks VALUES ('old', 'cline', 'tracker', '/src/tracker', 'old task', 1000, 2000, 0.5, 1, 1, 0, 0, 1, 0, 1, 2000);
+INSERT INTO api_requests VALUES ('old-0', 'old', 0, 1000, 1, 1, 0, 0, 2, 0.5, '');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, column := range columns {
+		var count int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", column.table, column.name).Scan(&count); err != nil || count != 1 {
+			t.Errorf("%s.%s not added: %v", column.table, column.name, err)
+		}
+	}
+
+	ledgers, err := s.RepositoryLedgers("tracker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledgers) != 1 || ledgers[0].Task.Prompt != "old task" || len(ledgers[0].APIRequests) != 1 || ledgers[0].APIRequests[0].Branch != "" {
+		t.Errorf("old task read back as %+v", ledgers)
+	}
+	// New tasks can be stored next to the old ones
+	if err := s.UpsertLedger(testLedger("new", "tracker", time.UnixMilli(3000))); err != nil {
+		t.Fatal(err)
+	}
+	// Opening again leaves the migrated database as it is
+	s.Close()
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+}
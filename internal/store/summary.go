@@ -0,0 +1,203 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+// ParseSince parses the start of a query window: a number of days ("7d"), a Go
+// duration ("12h"), a date ("2025-07-28") or an RFC 3339 timestamp
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return now.AddDate(0, 0, -7), nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid since value: %s", value)
+}
+
+// summaryCommitLimit is the number of commits listed by SpendSummary
+const summaryCommitLimit = 20
+
+// SpendSummary renders the spend per repository since the given time as text,
+// followed by the spend per branch and the most expensive commits in USD.
+// When converter is not nil each request is also converted at its own date and
+// the repository totals are shown in the display currency.
+func (s *Store) SpendSummary(since time.Time, converter *uilogparser.CurrencyConverter) (string, error) {
+	spend, err := s.SpendByRepository(since)
+	if err != nil {
+		return "", err
+	}
+	var converted map[string]float64
+	var unconverted int
+	if converter != nil {
+		if converted, unconverted, err = s.convertedSpendByRepository(since, converter); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spend since %s\n", since.Format("2006-01-02 15:04"))
+	if len(spend) == 0 {
+		fmt.Fprintf(&b, "  (no API requests)\n")
+		return b.String(), nil
+	}
+
+	var total, convertedTotal float64
+	for _, r := range spend {
+		repository := r.Repository
+		if repository == "" {
+			repository = "(unknown repository)"
+		}
+		if converter != nil {
+			fmt.Fprintf(&b, "  $%10.6f  %12.6f %s  %3d tasks  %4d requests  %s\n", r.Cost, converted[r.Repository], converter.Currency, r.Tasks, r.APIRequests, repository)
+		} else {
+			fmt.Fprintf(&b, "  $%10.6f  %3d tasks  %4d requests  %s\n", r.Cost, r.Tasks, r.APIRequests, repository)
+		}
+		total += r.Cost
+		convertedTotal += converted[r.Repository]
+	}
+	if converter != nil {
+		fmt.Fprintf(&b, "  $%10.6f  %12.6f %s  total\n", total, convertedTotal, converter.Currency)
+		if unconverted > 0 {
+			fmt.Fprintf(&b, "  %d requests have no %s rate on or before their date and are left out of the %s totals\n", unconverted, converter.Currency, converter.Currency)
+		}
+	} else {
+		fmt.Fprintf(&b, "  $%10.6f  total\n", total)
+	}
+
+	branches, err := s.SpendByBranch(since)
+	if err != nil {
+		return "", err
+	}
+	if len(branches) > 0 {
+		fmt.Fprintf(&b, "\nSpend by branch\n")
+		for _, r := range branches {
+			branch := r.Branch
+			if branch == "" {
+				branch = "(detached)"
+			}
+			fmt.Fprintf(&b, "  $%10.6f  %3d tasks  %4d requests  %s %s\n", r.Cost, r.Tasks, r.APIRequests, r.Repository, branch)
+		}
+	}
+
+	commits, err := s.SpendByCommit(since, summaryCommitLimit)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) > 0 {
+		fmt.Fprintf(&b, "\nMost expensive commits\n")
+		for _, c := range commits {
+			fmt.Fprintf(&b, "  $%10.6f  %4d requests  %s %s  %s\n", c.Cost, c.APIRequests, c.Repository, uilogparser.ShortHash(c.Hash), c.Subject)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// convertedSpendByRepository returns the converted cost of API requests made
+// since the given time per repository, and the number of requests left out
+// because they have no rate. Requests are converted one by one because the
+// rate depends on each request's date.
+func (s *Store) convertedSpendByRepository(since time.Time, converter *uilogparser.CurrencyConverter) (map[string]float64, int, error) {
+	rows, err := s.db.Query(`SELECT t.repository, r.timestamp, r.cost
+		FROM api_requests r JOIN tasks t ON t.task_id = r.task_id
+		WHERE r.timestamp >= ?`, since.UnixMilli())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query spend: %v", err)
+	}
+	defer rows.Close()
+
+	converted := make(map[string]float64)
+	var unconverted int
+	for rows.Next() {
+		var repository string
+		var timestamp int64
+		var cost float64
+		if err := rows.Scan(&repository, &timestamp, &cost); err != nil {
+			return nil, 0, fmt.Errorf("failed to read spend: %v", err)
+		}
+		amount, err := converter.Convert(cost, timestamp)
+		if err != nil {
+			unconverted++
+			continue
+		}
+		converted[repository] += amount
+	}
+	return converted, unconverted, rows.Err()
+}
+
+// WriteRepositoryHTMLReport writes an HTML dashboard covering every stored
+// task of a repository. When outputPath is empty the report goes to the logs
+// directory opts selects for the repository's most recent working directory.
+// Returns the path written.
+func (s *Store) WriteRepositoryHTMLReport(repository, outputPath string, opts uilogparser.Options, reportOpts uilogparser.HTMLReportOptions) (string, error) {
+	ledgers, err := s.RepositoryLedgers(repository)
+	if err != nil {
+		return "", err
+	}
+	if len(ledgers) == 0 {
+		return "", fmt.Errorf("no tasks recorded for repository %q", repository)
+	}
+
+	workingDir := ledgers[len(ledgers)-1].Task.WorkingDirectory
+	// Redaction replaces the home directory with ~
+	if strings.HasPrefix(workingDir, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			workingDir = filepath.Join(homeDir, workingDir[2:])
+		}
+	}
+
+	// The repository's overrides apply to its report as to its tasks
+	repoConfig, err := uilogparser.LoadRepositoryConfig(workingDir)
+	if err != nil {
+		return "", err
+	}
+	if repoConfig != nil && repoConfig.Disabled {
+		return "", fmt.Errorf("%w in %s", uilogparser.ErrTrackingDisabled, repoConfig.Path)
+	}
+
+	if outputPath == "" {
+		if workingDir == "" && opts.OutputLocation != uilogparser.OutputLocationCentral && opts.OutputLocation != uilogparser.OutputLocationData {
+			return "", fmt.Errorf("no working directory recorded for repository %q, pass an output path", repository)
+		}
+		if opts, err = repoConfig.Apply(opts, workingDir); err != nil {
+			return "", err
+		}
+		logsDir, err := opts.LogsDirectory(workingDir)
+		if err != nil {
+			return "", err
+		}
+		if err := opts.CreateLogsDirectory(logsDir); err != nil {
+			return "", err
+		}
+		outputPath = filepath.Join(logsDir, fmt.Sprintf("repository_%s_report.html", repository))
+	}
+
+	title := fmt.Sprintf("Repository %s cost report", repository)
+	if err := uilogparser.WriteHTMLReport(outputPath, title, ledgers, reportOpts); err != nil {
+		return "", err
+	}
+	return outputPath, nil
+}
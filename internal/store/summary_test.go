@@ -0,0 +1,59 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	uilogparser "github.com/mcbadger88/cline-task-cost-tracker/internal/ui-log-parser"
+)
+
+func TestSpendSummaryLeavesOutUnconvertedRequests(t *testing.T) {
+	s := openTest(t)
+	before := time.Date(2025, 6, 30, 9, 0, 0, 0, time.UTC)
+	after := time.Date(2025, 7, 28, 9, 0, 0, 0, time.UTC)
+	for _, ledger := range []*uilogparser.Ledger{
+		testLedger("1", "tracker", before, 1),
+		testLedger("2", "tracker", after, 0.5, 0.5),
+		testLedger("3", "docs", after, 2),
+	} {
+		if err := s.UpsertLedger(ledger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rates := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(rates, []byte("date,currency,rate\n2025-07-01,AUD,1.50\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter, err := uilogparser.NewCurrencyConverter("AUD", rates, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted, unconverted, err := s.convertedSpendByRepository(before, converter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unconverted != 1 {
+		t.Errorf("unconverted = %d, want 1", unconverted)
+	}
+	if converted["tracker"] != 1.5 || converted["docs"] != 3 {
+		t.Errorf("converted = %v, want tracker 1.5 and docs 3", converted)
+	}
+
+	summary, err := s.SpendSummary(before, converter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"    4.500000 AUD  total",
+		"1 requests have no AUD rate",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package uilogparser
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// FormatAnonymized writes a bundle with free text removed and identifying
+// values hashed, for sharing outside the team
+const FormatAnonymized = "anonymized"
+
+// Environment variables that configure anonymized exports
+const (
+	AnonymizeSaltEnvVar   = "COST_TRACKER_ANON_SALT"
+	AnonymizeBucketEnvVar = "COST_TRACKER_ANON_BUCKET"
+)
+
+// DefaultAnonymizeBucket is the granularity timestamps are rounded down to
+const DefaultAnonymizeBucket = time.Hour
+
+// anonymizedHashLength is the number of hex characters kept from each hash
+const anonymizedHashLength = 16
+
+// Anonymizer strips free text from ledgers, replaces task IDs, repository
+// names, paths and cost centres with salted hashes and buckets timestamps
+type Anonymizer struct {
+	salt   []byte
+	bucket time.Duration
+}
+
+// NewAnonymizer returns an anonymizer. The salt is required so hashes cannot
+// be reversed by hashing guessed names; keep it to join later exports. A zero
+// bucket uses DefaultAnonymizeBucket, and timestamps have millisecond
+// precision, so smaller buckets are rejected.
+func NewAnonymizer(salt string, bucket time.Duration) (*Anonymizer, error) {
+	if salt == "" {
+		return nil, fmt.Errorf("anonymized export needs a salt, set %s", AnonymizeSaltEnvVar)
+	}
+	if bucket == 0 {
+		bucket = DefaultAnonymizeBucket
+	}
+	if bucket < time.Millisecond {
+		return nil, fmt.Errorf("anonymize bucket must be at least 1ms, got %s", bucket)
+	}
+	return &Anonymizer{salt: []byte(salt), bucket: bucket}, nil
+}
+
+// Hash returns the salted hash of a value, empty for an empty value
+func (a *Anonymizer) Hash(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:anonymizedHashLength]
+}
+
+// hashPath hashes a path, keeping its extension so costs by language survive
+func (a *Anonymizer) hashPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return a.Hash(path) + filepath.Ext(path)
+}
+
+// AnonymizeLedger returns a copy of the ledger safe to share. Every timestamp
+// is rounded down to a bucket boundary, so the order of messages is kept by
+// their index and times only as finely as the bucket.
+func (a *Anonymizer) AnonymizeLedger(ledger *Ledger) *Ledger {
+	bucketMillis := a.bucket.Milliseconds()
+	shiftTime := func(ms int64) int64 {
+		return ms - ms%bucketMillis
+	}
+
+	taskID := a.Hash(ledger.Task.TaskID)
+	anonymized := &Ledger{
+		Task:        ledger.Task,
+		APIRequests: make([]LedgerAPIRequest, len(ledger.APIRequests)),
+		ToolCalls:   make([]LedgerToolCall, len(ledger.ToolCalls)),
+		Messages:    make([]LedgerMessage, len(ledger.Messages)),
+		Commits:     make([]LedgerCommit, len(ledger.Commits)),
+	}
+	anonymized.Task.TaskID = taskID
+	anonymized.Task.Repository = a.Hash(ledger.Task.Repository)
+	anonymized.Task.WorkingDirectory = a.Hash(ledger.Task.WorkingDirectory)
+	anonymized.Task.RepositoryRoot = a.Hash(ledger.Task.RepositoryRoot)
+	anonymized.Task.Subdirectory = a.Hash(ledger.Task.Subdirectory)
+	anonymized.Task.CostCentre = a.Hash(ledger.Task.CostCentre)
+	anonymized.Task.WorkspaceRoots = make([]string, len(ledger.Task.WorkspaceRoots))
+	for i, root := range ledger.Task.WorkspaceRoots {
+		anonymized.Task.WorkspaceRoots[i] = a.Hash(root)
+	}
+	anonymized.Task.StartBranch = a.Hash(ledger.Task.StartBranch)
+	anonymized.Task.StartCommit = a.Hash(ledger.Task.StartCommit)
+	anonymized.Task.EndBranch = a.Hash(ledger.Task.EndBranch)
+	anonymized.Task.EndCommit = a.Hash(ledger.Task.EndCommit)
+	anonymized.Task.Prompt = ""
+	anonymized.Task.StartedAt = shiftTime(ledger.Task.StartedAt)
+	anonymized.Task.EndedAt = shiftTime(ledger.Task.EndedAt)
+
+	for i, req := range ledger.APIRequests {
+		req.ID = LedgerID(taskID, req.MessageIndex)
+		req.TaskID = taskID
+		req.Timestamp = shiftTime(req.Timestamp)
+		req.Branch = a.Hash(req.Branch)
+		req.Commit = a.Hash(req.Commit)
+		anonymized.APIRequests[i] = req
+	}
+	requestIndexes := make(map[string]int, len(ledger.APIRequests))
+	for _, req := range ledger.APIRequests {
+		requestIndexes[req.ID] = req.MessageIndex
+	}
+	for i, call := range ledger.ToolCalls {
+		call.ID = LedgerID(taskID, call.MessageIndex)
+		call.TaskID = taskID
+		if index, ok := requestIndexes[call.RequestID]; ok {
+			call.RequestID = LedgerID(taskID, index)
+		}
+		call.Timestamp = shiftTime(call.Timestamp)
+		call.Path = a.hashPath(call.Path)
+		anonymized.ToolCalls[i] = call
+	}
+	for i, msg := range ledger.Messages {
+		msg.ID = LedgerID(taskID, msg.MessageIndex)
+		msg.TaskID = taskID
+		msg.Timestamp = shiftTime(msg.Timestamp)
+		msg.Text = ""
+		anonymized.Messages[i] = msg
+	}
+	for i, commit := range ledger.Commits {
+		commit.Hash = a.Hash(commit.Hash)
+		commit.ID = taskID + "-" + commit.Hash
+		commit.TaskID = taskID
+		commit.Branch = a.Hash(commit.Branch)
+		commit.Timestamp = shiftTime(commit.Timestamp)
+		commit.Subject = ""
+		anonymized.Commits[i] = commit
+	}
+	return anonymized
+}
+
+// WriteAnonymizedBundle anonymizes ledgers and writes them as a bundle
+func WriteAnonymizedBundle(dir string, ledgers []*Ledger, anonymizer *Anonymizer) error {
+	anonymized := make([]*Ledger, len(ledgers))
+	for i, ledger := range ledgers {
+		anonymized[i] = anonymizer.AnonymizeLedger(ledger)
+	}
+	return writeBundle(dir, anonymized, true)
+}
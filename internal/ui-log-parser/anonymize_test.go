@@ -0,0 +1,101 @@
+package uilogparser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnonymizerHash(t *testing.T) {
+	first, err := NewAnonymizer("salt", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := NewAnonymizer("salt", time.Minute)
+	other, _ := NewAnonymizer("pepper", 0)
+
+	hash := first.Hash("tracker")
+	if len(hash) != anonymizedHashLength || hash == "tracker" {
+		t.Errorf("Hash(tracker) = %q", hash)
+	}
+	if got := again.Hash("tracker"); got != hash {
+		t.Errorf("same salt hashed to %q and %q", hash, got)
+	}
+	if got := other.Hash("tracker"); got == hash {
+		t.Errorf("different salts both hashed to %q", got)
+	}
+	if got := first.Hash(""); got != "" {
+		t.Errorf("Hash(\"\") = %q, want empty", got)
+	}
+	if got := first.hashPath("/src/tracker/main.go"); !strings.HasSuffix(got, ".go") || strings.Contains(got, "tracker") {
+		t.Errorf("hashPath kept %q", got)
+	}
+}
+
+func TestNewAnonymizerBucket(t *testing.T) {
+	if _, err := NewAnonymizer("", 0); err == nil {
+		t.Error("empty salt accepted")
+	}
+	for _, bucket := range []time.Duration{500 * time.Microsecond, -time.Hour} {
+		if _, err := NewAnonymizer("salt", bucket); err == nil {
+			t.Errorf("bucket %s accepted", bucket)
+		}
+	}
+	anonymizer, err := NewAnonymizer("salt", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anonymizer.bucket != DefaultAnonymizeBucket {
+		t.Errorf("zero bucket = %s, want %s", anonymizer.bucket, DefaultAnonymizeBucket)
+	}
+	if _, err := NewAnonymizer("salt", time.Millisecond); err != nil {
+		t.Errorf("1ms bucket rejected: %v", err)
+	}
+}
+
+func TestAnonymizeLedger(t *testing.T) {
+	ledger := sampleLedger()
+	anonymizer, err := NewAnonymizer("salt", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := anonymizer.AnonymizeLedger(ledger)
+
+	taskID := anonymizer.Hash(ledger.Task.TaskID)
+	if got.Task.TaskID != taskID || got.Task.Repository != anonymizer.Hash("tracker") || got.Task.CostCentre != anonymizer.Hash("platform") {
+		t.Errorf("task identifiers not hashed: %+v", got.Task)
+	}
+	if got.Task.Prompt != "" || got.Messages[1].Text != "" || got.Commits[0].Subject != "" {
+		t.Error("free text kept")
+	}
+	if got.Task.TotalCost != ledger.Task.TotalCost || got.APIRequests[1].TokensIn != 200 || got.ToolCalls[0].Tool != "read_file" {
+		t.Error("costs, tokens or tool names changed")
+	}
+	if want := LedgerID(taskID, 0); got.ToolCalls[0].RequestID != want {
+		t.Errorf("tool call request = %q, want %q", got.ToolCalls[0].RequestID, want)
+	}
+	if ledger.Task.CostCentre != "platform" || ledger.Messages[1].Text == "" {
+		t.Error("original ledger modified")
+	}
+
+	minute := func(hour, min int) int64 {
+		return time.Date(2025, 7, 28, hour, min, 0, 0, time.UTC).UnixMilli()
+	}
+	for _, tt := range []struct {
+		name string
+		got  int64
+		want int64
+	}{
+		{"task start", got.Task.StartedAt, minute(9, 52)},
+		{"task end", got.Task.EndedAt, minute(9, 53)},
+		{"first request", got.APIRequests[0].Timestamp, minute(9, 52)},
+		{"second request", got.APIRequests[1].Timestamp, minute(9, 53)},
+		{"tool call", got.ToolCalls[0].Timestamp, minute(9, 52)},
+		{"message", got.Messages[2].Timestamp, minute(9, 53)},
+		{"commit", got.Commits[0].Timestamp, minute(9, 53)},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, time.UnixMilli(tt.got).UTC(), time.UnixMilli(tt.want).UTC())
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package uilogparser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatBundle writes a directory of normalized tables instead of one records file
+const FormatBundle = "bundle"
+
+// BundleSchemaVersion is bumped whenever bundle columns change incompatibly
+const BundleSchemaVersion = 1
+
+// bundleTimeLayout is the layout of timestamp columns in bundle tables
+const bundleTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// BundleColumn describes one column of a bundle table in schema.json
+type BundleColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	References  string `json:"references,omitempty"`
+}
+
+// BundleTable describes one CSV file of a bundle in schema.json
+type BundleTable struct {
+	Name       string         `json:"name"`
+	File       string         `json:"file"`
+	PrimaryKey string         `json:"primary_key"`
+	Columns    []BundleColumn `json:"columns"`
+}
+
+// BundleSchema is the content of schema.json
+type BundleSchema struct {
+	Version int `json:"version"`
+	// Anonymized is set when free text is empty and identifying values are
+	// salted hashes
+	Anonymized bool          `json:"anonymized"`
+	Tables     []BundleTable `json:"tables"`
+}
+
+// bundleTables lists the tables of a bundle in the order they are written
+var bundleTables = []BundleTable{
+	{
+		Name: "tasks", File: "tasks.csv", PrimaryKey: "task_id",
+		Columns: []BundleColumn{
+			{Name: "task_id", Type: "string", Description: "Task ID, the name of the task directory"},
+			{Name: "source", Type: "string", Description: "Tool that produced the transcript"},
+			{Name: "repository", Type: "string", Description: "Name of the repository the task worked in"},
+			{Name: "working_directory", Type: "string", Description: "Most recent working directory of the task"},
+			{Name: "prompt", Type: "string", Description: "Task as first given"},
+			{Name: "started_at", Type: "timestamp", Description: "Time of the first message"},
+			{Name: "ended_at", Type: "timestamp", Description: "Time of the last message"},
+			{Name: "total_cost", Type: "decimal", Description: "Cost of all API requests in USD"},
+			{Name: "tokens_in", Type: "integer", Description: "Input tokens of all API requests"},
+			{Name: "tokens_out", Type: "integer", Description: "Output tokens of all API requests"},
+			{Name: "cache_writes", Type: "integer", Description: "Cache write tokens of all API requests"},
+			{Name: "cache_reads", Type: "integer", Description: "Cache read tokens of all API requests"},
+			{Name: "api_requests", Type: "integer", Description: "Number of API requests"},
+			{Name: "tool_calls", Type: "integer", Description: "Number of tool calls"},
+			{Name: "messages", Type: "integer", Description: "Number of UI messages"},
+			{Name: "cost_centre", Type: "string", Description: "Cost centre tag set by the repository config"},
+			{Name: "repository_root", Type: "string", Description: "Top of the checkout holding the working directory"},
+			{Name: "subdirectory", Type: "string", Description: "Working directory relative to the repository root, empty at the root"},
+			{Name: "workspace_roots", Type: "string", Description: "Roots of the task's workspace, one per line, the working directory first"},
+			{Name: "start_branch", Type: "string", Description: "Git branch checked out when the task started, empty when detached or outside git"},
+			{Name: "start_commit", Type: "string", Description: "Git commit checked out when the task started"},
+			{Name: "end_branch", Type: "string", Description: "Git branch checked out when the task ended, empty when detached or outside git"},
+			{Name: "end_commit", Type: "string", Description: "Git commit checked out when the task ended"},
+			{Name: "redacted", Type: "boolean", Description: "Whether secrets and personal data were masked before export"},
+			{Name: "redactions", Type: "string", Description: "Matches masked per redaction rule, e.g. email:2;token:1"},
+		},
+	},
+	{
+		Name: "api_requests", File: "api_requests.csv", PrimaryKey: "id",
+		Columns: []BundleColumn{
+			{Name: "id", Type: "string", Description: "Stable ID, {task_id}-{message_index}"},
+			{Name: "task_id", Type: "string", Description: "Task that made the request", References: "tasks.task_id"},
+			{Name: "message_index", Type: "integer", Description: "Position of the api_req_started message in the task"},
+			{Name: "timestamp", Type: "timestamp", Description: "Time the request started"},
+			{Name: "tokens_in", Type: "integer", Description: "Input tokens"},
+			{Name: "tokens_out", Type: "integer", Description: "Output tokens"},
+			{Name: "cache_writes", Type: "integer", Description: "Cache write tokens"},
+			{Name: "cache_reads", Type: "integer", Description: "Cache read tokens"},
+			{Name: "context_tokens", Type: "integer", Description: "Size of the context window after the request"},
+			{Name: "cost", Type: "decimal", Description: "Cost of the request in USD"},
+			{Name: "cancel_reason", Type: "string", Description: "Why the request was cancelled, empty if it completed"},
+			{Name: "branch", Type: "string", Description: "Git branch checked out when the request was made"},
+			{Name: "commit", Type: "string", Description: "First commit created during the task after the request, empty if its work was not committed", References: "commits.hash"},
+		},
+	},
+	{
+		Name: "tool_calls", File: "tool_calls.csv", PrimaryKey: "id",
+		Columns: []BundleColumn{
+			{Name: "id", Type: "string", Description: "Stable ID, {task_id}-{message_index}"},
+			{Name: "task_id", Type: "string", Description: "Task that made the call", References: "tasks.task_id"},
+			{Name: "request_id", Type: "string", Description: "API request that issued the call, empty before the first request", References: "api_requests.id"},
+			{Name: "message_index", Type: "integer", Description: "Position of the tool message in the task"},
+			{Name: "timestamp", Type: "timestamp", Description: "Time of the tool message"},
+			{Name: "tool", Type: "string", Description: "Canonical tool name, e.g. read_file"},
+			{Name: "path", Type: "string", Description: "File or directory the tool acted on"},
+		},
+	},
+	{
+		Name: "messages", File: "messages.csv", PrimaryKey: "id",
+		Columns: []BundleColumn{
+			{Name: "id", Type: "string", Description: "Stable ID, {task_id}-{message_index}"},
+			{Name: "task_id", Type: "string", Description: "Task the message belongs to", References: "tasks.task_id"},
+			{Name: "message_index", Type: "integer", Description: "Position of the message in the task"},
+			{Name: "timestamp", Type: "timestamp", Description: "Time of the message"},
+			{Name: "type", Type: "string", Description: "say or ask"},
+			{Name: "kind", Type: "string", Description: "Value of the say or ask field, e.g. api_req_started"},
+			{Name: "text", Type: "string", Description: "Raw message text"},
+		},
+	},
+	{
+		Name: "commits", File: "commits.csv", PrimaryKey: "id",
+		Columns: []BundleColumn{
+			{Name: "id", Type: "string", Description: "Stable ID, {task_id}-{hash}"},
+			{Name: "task_id", Type: "string", Description: "Task the commit was created during", References: "tasks.task_id"},
+			{Name: "hash", Type: "string", Description: "Commit hash"},
+			{Name: "branch", Type: "string", Description: "Branch the commit was created on, empty when detached"},
+			{Name: "timestamp", Type: "timestamp", Description: "Time HEAD moved to the commit"},
+			{Name: "subject", Type: "string", Description: "First line of the commit message"},
+			{Name: "api_requests", Type: "integer", Description: "Number of API requests made since the previous commit of the task"},
+			{Name: "cost", Type: "decimal", Description: "Cost of those API requests in USD"},
+		},
+	},
+}
+
+// WriteBundle writes ledgers as a directory of normalized CSV tables joined by
+// stable IDs, with a schema.json describing every column
+func WriteBundle(dir string, ledgers []*Ledger) error {
+	return writeBundle(dir, ledgers, false)
+}
+
+// writeBundle writes the bundle tables and schema.json
+func writeBundle(dir string, ledgers []*Ledger, anonymized bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	rows := map[string][][]string{}
+	for _, ledger := range ledgers {
+		task := ledger.Task
+		rows["tasks"] = append(rows["tasks"], []string{
+			task.TaskID, task.Source, task.Repository, task.WorkingDirectory, task.Prompt,
+			bundleTime(task.StartedAt), bundleTime(task.EndedAt), bundleCost(task.TotalCost),
+			bundleInt(task.TokensIn), bundleInt(task.TokensOut), bundleInt(task.CacheWrites), bundleInt(task.CacheReads),
+			strconv.Itoa(task.APIRequests), strconv.Itoa(task.ToolCalls), strconv.Itoa(task.Messages),
+			task.CostCentre, task.RepositoryRoot, task.Subdirectory, strings.Join(task.WorkspaceRoots, "\n"),
+			task.StartBranch, task.StartCommit, task.EndBranch, task.EndCommit,
+			strconv.FormatBool(task.Redacted), task.Redactions,
+		})
+		for _, req := range ledger.APIRequests {
+			rows["api_requests"] = append(rows["api_requests"], []string{
+				req.ID, req.TaskID, strconv.Itoa(req.MessageIndex), bundleTime(req.Timestamp),
+				bundleInt(req.TokensIn), bundleInt(req.TokensOut), bundleInt(req.CacheWrites), bundleInt(req.CacheReads),
+				bundleInt(req.ContextTokens), bundleCost(req.Cost), req.CancelReason, req.Branch, req.Commit,
+			})
+		}
+		for _, call := range ledger.ToolCalls {
+			rows["tool_calls"] = append(rows["tool_calls"], []string{
+				call.ID, call.TaskID, call.RequestID, strconv.Itoa(call.MessageIndex), bundleTime(call.Timestamp),
+				call.Tool, call.Path,
+			})
+		}
+		for _, msg := range ledger.Messages {
+			rows["messages"] = append(rows["messages"], []string{
+				msg.ID, msg.TaskID, strconv.Itoa(msg.MessageIndex), bundleTime(msg.Timestamp),
+				msg.Type, msg.Kind, msg.Text,
+			})
+		}
+		for _, commit := range ledger.Commits {
+			rows["commits"] = append(rows["commits"], []string{
+				commit.ID, commit.TaskID, commit.Hash, commit.Branch, bundleTime(commit.Timestamp),
+				commit.Subject, strconv.Itoa(commit.Requests), bundleCost(commit.Cost),
+			})
+		}
+	}
+
+	for _, table := range bundleTables {
+		if err := writeBundleTable(filepath.Join(dir, table.File), table, rows[table.Name]); err != nil {
+			return fmt.Errorf("failed to write %s: %v", table.File, err)
+		}
+	}
+
+	schema, err := json.MarshalIndent(BundleSchema{Version: BundleSchemaVersion, Anonymized: anonymized, Tables: bundleTables}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "schema.json"), append(schema, '\n'), 0644)
+}
+
+// writeBundleTable writes one table with its header row
+func writeBundleTable(filename string, table BundleTable, rows [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	header := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// bundleTime formats a Unix millisecond timestamp for a bundle table
+func bundleTime(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(bundleTimeLayout)
+}
+
+// bundleInt formats an integer for a bundle table
+func bundleInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+// bundleCost formats a USD cost for a bundle table
+func bundleCost(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// BundleWriter writes a task as a bundle directory. It needs the task ledger,
+// so it implements LedgerWriter and cannot write bare records. With an
+// Anonymizer the bundle is anonymized.
+type BundleWriter struct {
+	Anonymizer *Anonymizer
+}
+
+// Write fails, bundles are written from the ledger with WriteLedger
+func (BundleWriter) Write(filename string, records []CostRecord) error {
+	return fmt.Errorf("the %s format is written from the task ledger", FormatBundle)
+}
+
+// WriteLedger writes the task's bundle into the directory at path
+func (w BundleWriter) WriteLedger(path string, ledger *Ledger) error {
+	if w.Anonymizer != nil {
+		return WriteAnonymizedBundle(path, []*Ledger{ledger}, w.Anonymizer)
+	}
+	return WriteBundle(path, []*Ledger{ledger})
+}
+
+// Extension returns the suffix of bundle directories
+func (w BundleWriter) Extension() string {
+	if w.Anonymizer != nil {
+		return "_anonymized"
+	}
+	return "_bundle"
+}
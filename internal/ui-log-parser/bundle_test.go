@@ -0,0 +1,135 @@
+package uilogparser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sampleLedger returns the ledger of a small task that touched every table
+func sampleLedger() *Ledger {
+	start := time.Date(2025, 7, 28, 9, 52, 1, 0, time.UTC).UnixMilli()
+	return &Ledger{
+		Task: LedgerTask{
+			TaskID: "1753696321000", Source: SourceCline, Repository: "tracker",
+			WorkingDirectory: "/Users/emma/src/tracker/cmd", RepositoryRoot: "/Users/emma/src/tracker", Subdirectory: "cmd",
+			WorkspaceRoots: []string{"/Users/emma/src/tracker/cmd", "/Users/emma/src/docs"}, CostCentre: "platform",
+			StartBranch: "main", StartCommit: "aaaa", EndBranch: "main", EndCommit: "bbbb",
+			Prompt: "fix the parser for emma@example.com", StartedAt: start, EndedAt: start + 90_000,
+			TotalCost: 0.75, TokensIn: 300, TokensOut: 30, CacheWrites: 10, CacheReads: 100,
+			APIRequests: 2, ToolCalls: 1, Messages: 3,
+		},
+		APIRequests: []LedgerAPIRequest{
+			{ID: "1753696321000-0", TaskID: "1753696321000", MessageIndex: 0, Timestamp: start, TokensIn: 100, TokensOut: 10, ContextTokens: 110, Cost: 0.25, Branch: "main", Commit: "bbbb"},
+			{ID: "1753696321000-2", TaskID: "1753696321000", MessageIndex: 2, Timestamp: start + 61_500, TokensIn: 200, TokensOut: 20, CacheWrites: 10, CacheReads: 100, ContextTokens: 330, Cost: 0.5, Branch: "main"},
+		},
+		ToolCalls: []LedgerToolCall{
+			{ID: "1753696321000-1", TaskID: "1753696321000", RequestID: "1753696321000-0", MessageIndex: 1, Timestamp: start + 30_000, Tool: "read_file", Path: "/Users/emma/src/tracker/main.go"},
+		},
+		Messages: []LedgerMessage{
+			{ID: "1753696321000-0", TaskID: "1753696321000", MessageIndex: 0, Timestamp: start, Type: "say", Kind: "api_req_started", Text: `{"cost":0.25}`},
+			{ID: "1753696321000-1", TaskID: "1753696321000", MessageIndex: 1, Timestamp: start + 30_000, Type: "say", Kind: "tool", Text: "read main.go\nfor emma@example.com"},
+			{ID: "1753696321000-2", TaskID: "1753696321000", MessageIndex: 2, Timestamp: start + 61_500, Type: "say", Kind: "api_req_started", Text: `{"cost":0.5}`},
+		},
+		Commits: []LedgerCommit{
+			{ID: "1753696321000-bbbb", TaskID: "1753696321000", Hash: "bbbb", Branch: "main", Timestamp: start + 60_000, Subject: "Fix parser", Requests: 1, Cost: 0.25},
+		},
+	}
+}
+
+// readBundleTable reads the rows of a bundle CSV file, header first
+func readBundleTable(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return rows
+}
+
+func TestWriteBundleMatchesSchema(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bundle")
+	if err := WriteBundle(dir, []*Ledger{sampleLedger()}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema BundleSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Version != BundleSchemaVersion || schema.Anonymized {
+		t.Errorf("schema version %d anonymized %v, want %d false", schema.Version, schema.Anonymized, BundleSchemaVersion)
+	}
+
+	wantRows := map[string]int{"tasks": 1, "api_requests": 2, "tool_calls": 1, "messages": 3, "commits": 1}
+	if len(schema.Tables) != len(wantRows) {
+		t.Fatalf("schema lists %d tables, want %d", len(schema.Tables), len(wantRows))
+	}
+	for _, table := range schema.Tables {
+		rows := readBundleTable(t, filepath.Join(dir, table.File))
+		var names []string
+		for _, column := range table.Columns {
+			names = append(names, column.Name)
+		}
+		if !reflect.DeepEqual(rows[0], names) {
+			t.Errorf("%s header = %q, schema lists %q", table.File, rows[0], names)
+		}
+		if got := len(rows) - 1; got != wantRows[table.Name] {
+			t.Errorf("%s has %d rows, want %d", table.File, got, wantRows[table.Name])
+		}
+		if !containsString(names, table.PrimaryKey) {
+			t.Errorf("%s primary key %s is not a column", table.Name, table.PrimaryKey)
+		}
+	}
+}
+
+func TestWriteBundleValues(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteBundle(dir, []*Ledger{sampleLedger()}); err != nil {
+		t.Fatal(err)
+	}
+	column := func(table string, row int, name string) string {
+		rows := readBundleTable(t, filepath.Join(dir, table+".csv"))
+		for i, header := range rows[0] {
+			if header == name {
+				return rows[row][i]
+			}
+		}
+		t.Fatalf("%s has no column %s", table, name)
+		return ""
+	}
+	tests := []struct {
+		table string
+		row   int
+		name  string
+		want  string
+	}{
+		{"tasks", 1, "started_at", "2025-07-28T09:52:01.000Z"},
+		{"tasks", 1, "total_cost", "0.750000"},
+		{"tasks", 1, "workspace_roots", "/Users/emma/src/tracker/cmd\n/Users/emma/src/docs"},
+		{"tasks", 1, "redacted", "false"},
+		{"api_requests", 2, "timestamp", "2025-07-28T09:53:02.500Z"},
+		{"api_requests", 2, "commit", ""},
+		{"tool_calls", 1, "request_id", "1753696321000-0"},
+		{"messages", 2, "text", "read main.go\nfor emma@example.com"},
+		{"commits", 1, "api_requests", "1"},
+	}
+	for _, tt := range tests {
+		if got := column(tt.table, tt.row, tt.name); got != tt.want {
+			t.Errorf("%s row %d %s = %q, want %q", tt.table, tt.row, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package uilogparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SourceClaudeCode identifies ledgers imported from Claude Code transcripts
+const SourceClaudeCode = "claude-code"
+
+// ClaudeConfigDirEnvVar is the environment variable Claude Code reads its
+// config directory from, ~/.claude when unset
+const ClaudeConfigDirEnvVar = "CLAUDE_CONFIG_DIR"
+
+// claudeTranscriptLine is one line of a Claude Code session transcript
+type claudeTranscriptLine struct {
+	Type             string          `json:"type"`
+	Subtype          string          `json:"subtype"`
+	Timestamp        string          `json:"timestamp"`
+	Cwd              string          `json:"cwd"`
+	IsCompactSummary bool            `json:"isCompactSummary"`
+	IsMeta           bool            `json:"isMeta"`
+	CostUSD          *float64        `json:"costUSD"`
+	Message          json.RawMessage `json:"message"`
+}
+
+// claudeMessage is the API message of a user or assistant transcript line
+type claudeMessage struct {
+	ID      string          `json:"id"`
+	Model   string          `json:"model"`
+	Content json.RawMessage `json:"content"`
+	Usage   *claudeUsage    `json:"usage"`
+}
+
+// claudeUsage is the usage block of an assistant message
+type claudeUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// claudeContentBlock is one block of a message's content
+type claudeContentBlock struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text"`
+	Name    string                 `json:"name"`
+	Input   map[string]interface{} `json:"input"`
+	Content json.RawMessage        `json:"content"`
+	IsError bool                   `json:"is_error"`
+}
+
+// claudeModelPrice is the price of a model in USD per million tokens
+type claudeModelPrice struct {
+	prefix      string
+	input       float64
+	output      float64
+	cacheWrites float64
+	cacheReads  float64
+}
+
+// claudeModelPrices are the list prices of the models Claude Code uses,
+// matched by model ID prefix. Cache writes are priced at the 5 minute TTL.
+var claudeModelPrices = []claudeModelPrice{
+	{"claude-opus-4-5", 5, 25, 6.25, 0.50},
+	{"claude-opus-4-1", 15, 75, 18.75, 1.50},
+	{"claude-opus-4-2", 15, 75, 18.75, 1.50},
+	{"claude-sonnet-4-5", 3, 15, 3.75, 0.30},
+	{"claude-sonnet-4-2", 3, 15, 3.75, 0.30},
+	{"claude-haiku-4-5", 1, 5, 1.25, 0.10},
+	{"claude-3-7-sonnet", 3, 15, 3.75, 0.30},
+	{"claude-3-5-sonnet", 3, 15, 3.75, 0.30},
+	{"claude-3-5-haiku", 0.80, 4, 1, 0.08},
+	{"claude-3-opus", 15, 75, 18.75, 1.50},
+	{"claude-3-haiku", 0.25, 1.25, 0.30, 0.03},
+}
+
+// claudeToolNames maps Claude Code's tools onto the tool payload names Cline
+// writes, so file costs and loop detection treat them alike
+var claudeToolNames = map[string]string{
+	"Read":         "readFile",
+	"Write":        "newFileCreated",
+	"Edit":         "editedExistingFile",
+	"MultiEdit":    "editedExistingFile",
+	"NotebookEdit": "editedExistingFile",
+	"LS":           "listFilesTopLevel",
+	"Glob":         "searchFiles",
+	"Grep":         "searchFiles",
+	"WebFetch":     "webFetch",
+}
+
+// ClaudeCodeProjectsDir returns the directory Claude Code keeps one directory
+// of session transcripts per project in
+func ClaudeCodeProjectsDir() string {
+	if configDir := os.Getenv(ClaudeConfigDirEnvVar); configDir != "" {
+		return filepath.Join(configDir, "projects")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude", "projects")
+}
+
+// FindClaudeCodeTranscripts returns the session transcripts under a projects
+// directory, oldest first
+func FindClaudeCodeTranscripts(projectsDir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(projectsDir, "*", "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	modTimes := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return modTimes[paths[i]].Before(modTimes[paths[j]])
+	})
+	return paths, nil
+}
+
+// claudeCodeCost prices the usage of one request to model. The second result
+// is false when the model has no known price.
+func claudeCodeCost(model string, usage claudeUsage) (float64, bool) {
+	for _, price := range claudeModelPrices {
+		if strings.HasPrefix(model, price.prefix) {
+			return (float64(usage.InputTokens)*price.input +
+				float64(usage.OutputTokens)*price.output +
+				float64(usage.CacheCreationInputTokens)*price.cacheWrites +
+				float64(usage.CacheReadInputTokens)*price.cacheReads) / 1e6, true
+		}
+	}
+	return 0, false
+}
+
+// ParseClaudeCodeTranscript reads a Claude Code session transcript and maps it
+// onto Cline's messages: the first prompt becomes the task, each assistant API
+// response an "api_req_started" priced from its usage block, and its text and
+// tool uses the matching say/ask messages. Responses split over several lines
+// share a message ID and are counted once.
+func ParseClaudeCodeTranscript(filePath string) ([]UIMessage, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	defer file.Close()
+	log.Printf("Processing file: %s", filePath)
+
+	var messages []UIMessage
+	// requests holds the payload of each "api_req_started" message by index,
+	// encoded once every line of the response has been read
+	requests := make(map[int]*APIRequest)
+	requestIndex := make(map[string]int)
+	unpriced := make(map[string]bool)
+
+	// Claude Code appends to the transcript while the session runs, so the
+	// last line may be cut off mid-write. An unparsable line is only an error
+	// when another line follows it.
+	var lineErr error
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if lineErr != nil {
+			return nil, lineErr
+		}
+		var line claudeTranscriptLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			lineErr = fmt.Errorf("error parsing JSON on line %d: %v", lineNumber, err)
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339Nano, line.Timestamp)
+		if err != nil {
+			// Summaries and other lines without a timestamp hold no usage
+			continue
+		}
+		at := ts.UnixMilli()
+
+		if line.Type == "system" && line.Subtype == "compact_boundary" {
+			messages = append(messages, UIMessage{Type: "say", Say: ContextCondensed, Timestamp: at})
+			continue
+		}
+		if len(line.Message) == 0 {
+			continue
+		}
+		var msg claudeMessage
+		if err := json.Unmarshal(line.Message, &msg); err != nil {
+			return nil, fmt.Errorf("error parsing message on line %d: %v", lineNumber, err)
+		}
+
+		switch line.Type {
+		case "user":
+			if line.IsMeta {
+				continue
+			}
+			if line.IsCompactSummary {
+				// Older versions write the summary without a boundary line
+				if len(messages) == 0 || messages[len(messages)-1].Say != ContextCondensed {
+					messages = append(messages, UIMessage{Type: "say", Say: ContextCondensed, Timestamp: at})
+				}
+				messages[len(messages)-1].Text = claudeText(msg.Content)
+				continue
+			}
+			messages = append(messages, claudeUserMessages(msg.Content, len(messages) == 0, at)...)
+
+		case "assistant":
+			if msg.Usage != nil && msg.Model != "<synthetic>" {
+				index, seen := requestIndex[msg.ID]
+				if !seen || msg.ID == "" {
+					index = len(messages)
+					requestIndex[msg.ID] = index
+					messages = append(messages, UIMessage{Type: "say", Say: "api_req_started", Timestamp: at})
+				}
+				// Later lines of a response carry the final output tokens
+				req := &APIRequest{
+					Request:     fmt.Sprintf("%s\n\n# Current Working Directory (%s)", msg.Model, line.Cwd),
+					TokensIn:    msg.Usage.InputTokens,
+					TokensOut:   msg.Usage.OutputTokens,
+					CacheWrites: msg.Usage.CacheCreationInputTokens,
+					CacheReads:  msg.Usage.CacheReadInputTokens,
+				}
+				if line.CostUSD != nil {
+					req.Cost = *line.CostUSD
+				} else if cost, ok := claudeCodeCost(msg.Model, *msg.Usage); ok {
+					req.Cost = cost
+				} else {
+					unpriced[msg.Model] = true
+				}
+				requests[index] = req
+			}
+			messages = append(messages, claudeAssistantMessages(msg.Content, line.Cwd, at)...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	if lineErr != nil {
+		log.Printf("Warning: skipping the last line of %s: %v", filePath, lineErr)
+	}
+
+	for index, req := range requests {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		messages[index].Text = string(data)
+	}
+	for model := range unpriced {
+		log.Printf("Warning: no price known for model %s, its requests are costed at $0", model)
+	}
+
+	log.Printf("Parsed %d messages", len(messages))
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in the file")
+	}
+	return messages, nil
+}
+
+// claudeUserMessages maps the content of a user line: typed text becomes the
+// task or user feedback, and failed tool results become errors
+func claudeUserMessages(content json.RawMessage, first bool, at int64) []UIMessage {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		kind := "user_feedback"
+		if first {
+			kind = "task"
+		}
+		return []UIMessage{{Type: "say", Say: kind, Text: text, Timestamp: at}}
+	}
+
+	var blocks []claudeContentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil
+	}
+	var messages []UIMessage
+	for _, block := range blocks {
+		switch {
+		case block.Type == "text":
+			kind := "user_feedback"
+			if first && len(messages) == 0 {
+				kind = "task"
+			}
+			messages = append(messages, UIMessage{Type: "say", Say: kind, Text: block.Text, Timestamp: at})
+		case block.Type == "tool_result" && block.IsError:
+			messages = append(messages, UIMessage{Type: "say", Say: "error", Text: claudeText(block.Content), Timestamp: at})
+		}
+	}
+	return messages
+}
+
+// claudeAssistantMessages maps the content blocks of an assistant line onto
+// text, tool and command messages
+func claudeAssistantMessages(content json.RawMessage, cwd string, at int64) []UIMessage {
+	var blocks []claudeContentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil
+	}
+	var messages []UIMessage
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			messages = append(messages, UIMessage{Type: "say", Say: "text", Text: block.Text, Timestamp: at})
+		case "thinking":
+			// Thinking is billed as output tokens of the response
+			continue
+		case "tool_use":
+			if block.Name == "Bash" {
+				command, _ := block.Input["command"].(string)
+				messages = append(messages, UIMessage{Type: "ask", Ask: "command", Text: command, Timestamp: at})
+				continue
+			}
+			messages = append(messages, UIMessage{Type: "say", Say: "tool", Text: claudeToolPayload(block, cwd), Timestamp: at})
+		}
+	}
+	return messages
+}
+
+// claudeToolPayload encodes a tool use as the payload of a Cline tool
+// message, with paths relative to the working directory as Cline writes them
+func claudeToolPayload(block claudeContentBlock, cwd string) string {
+	call := ToolCall{Tool: block.Name}
+	if name, ok := claudeToolNames[block.Name]; ok {
+		call.Tool = name
+	}
+	for _, key := range []string{"file_path", "notebook_path", "path", "url"} {
+		if path, ok := block.Input[key].(string); ok && path != "" {
+			call.Path = path
+			break
+		}
+	}
+	if cwd != "" && filepath.IsAbs(call.Path) {
+		if rel, err := filepath.Rel(cwd, call.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			call.Path = rel
+		}
+	}
+	switch block.Name {
+	case "Grep":
+		call.Regex, _ = block.Input["pattern"].(string)
+		call.FilePattern, _ = block.Input["glob"].(string)
+	case "Glob":
+		call.FilePattern, _ = block.Input["pattern"].(string)
+	}
+	data, err := json.Marshal(call)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// claudeText returns the text of content that is either a string or a list of
+// text blocks
+func claudeText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	var blocks []claudeContentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
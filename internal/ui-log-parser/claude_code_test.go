@@ -0,0 +1,51 @@
+package uilogparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseClaudeCodeTranscript(t *testing.T) {
+	prompt := `{"type":"user","timestamp":"2025-07-27T23:52:01Z","cwd":"/src/api","message":{"role":"user","content":"fix the build"}}`
+	response := `{"type":"assistant","timestamp":"2025-07-27T23:52:05Z","cwd":"/src/api","message":{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":1000000,"output_tokens":0}}}`
+	tests := []struct {
+		name     string
+		lines    []string
+		wantErr  bool
+		wantCost float64
+	}{
+		{"complete", []string{prompt, response}, false, 3},
+		{"last line cut off", []string{prompt, response, `{"type":"assistant","timest`}, false, 3},
+		{"unparsable line in the middle", []string{prompt, `{"type":`, response}, true, 0},
+		{"only a cut off line", []string{`{"type":"user"`}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "session.jsonl")
+			if err := os.WriteFile(path, []byte(strings.Join(tt.lines, "\n")), 0644); err != nil {
+				t.Fatal(err)
+			}
+			messages, err := ParseClaudeCodeTranscript(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClaudeCodeTranscript() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if messages[0].Say != "task" || messages[0].Text != "fix the build" {
+				t.Errorf("first message is %+v, want the task", messages[0])
+			}
+			var cost float64
+			for _, msg := range messages {
+				if req, ok := ParseAPIRequest(msg); ok {
+					cost += req.Cost
+				}
+			}
+			if cost != tt.wantCost {
+				t.Errorf("cost = %v, want %v", cost, tt.wantCost)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package uilogparser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// detachedBranch names the branch of requests made with a detached HEAD
+const detachedBranch = "(detached)"
+
+// commitHash matches the abbreviated or full hash a detached HEAD is moved to
+var commitHash = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+// GitHead is what a repository had checked out
+type GitHead struct {
+	// Branch is empty when HEAD is detached
+	Branch string
+	// Commit is empty on a branch without commits
+	Commit string
+}
+
+// headChange is an entry of the HEAD reflog: where HEAD moved from and to,
+// and when
+type headChange struct {
+	Timestamp int64
+	From      string
+	Head      GitHead
+	Message   string
+}
+
+// GitActivity is what happened in the repository of a task while it ran: the
+// branch and commit at its start and end, and the commits created in between
+type GitActivity struct {
+	Start GitHead
+	End   GitHead
+	// Commits are the commits created during the task, oldest first
+	Commits []LedgerCommit
+
+	// changes are the moves of HEAD in the reflog, oldest first, and before
+	// is HEAD before the first of them. Without a reflog HEAD is taken to be
+	// Start throughout.
+	changes []headChange
+	before  GitHead
+}
+
+// ReadGitActivity reads the branch and commit checked out in the repository
+// holding workingDir at the start and end of a task, and the commits created
+// between them. HEAD, refs and the HEAD reflog are read from the git
+// directory, falling back to the local git for repositories it cannot read,
+// such as ones without a reflog. It returns nil when workingDir is not inside
+// a git repository.
+func ReadGitActivity(workingDir string, start, end int64) (*GitActivity, error) {
+	if workingDir == "" || !filepath.IsAbs(workingDir) {
+		return nil, nil
+	}
+	root, ok := gitRoot(filepath.Clean(workingDir))
+	if !ok {
+		return nil, nil
+	}
+	dotGit, err := gitDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := readGitHead(dotGit)
+	if err != nil {
+		if head, err = gitHeadFromCLI(root); err != nil {
+			return nil, err
+		}
+	}
+	changes, before, err := readHeadReflog(dotGit, head)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &GitActivity{Start: head, End: head, changes: changes, before: before}
+	if len(changes) == 0 {
+		// Without a reflog only the commits made in the window are known,
+		// and a branch without commits has none
+		if head.Commit == "" {
+			return activity, nil
+		}
+		if activity.Commits, err = gitCommitsFromCLI(root, head.Branch, start, end); err != nil {
+			return nil, err
+		}
+		return activity, nil
+	}
+
+	// The reflog has second precision, so a move of HEAD in the second of the
+	// first message counts as made during the task
+	startSecond := start / 1000 * 1000
+	activity.Start = activity.HeadAt(startSecond - 1)
+	activity.End = activity.HeadAt(end)
+	for _, change := range changes {
+		if change.Timestamp < startSecond || change.Timestamp > end {
+			continue
+		}
+		subject, ok := createdCommit(change.Message)
+		if !ok {
+			continue
+		}
+		// An amended commit replaces the one HEAD was on
+		if strings.HasPrefix(change.Message, "commit (amend):") {
+			activity.Commits = removeCommit(activity.Commits, change.From)
+		}
+		activity.Commits = append(activity.Commits, LedgerCommit{
+			Hash:      change.Head.Commit,
+			Branch:    change.Head.Branch,
+			Timestamp: change.Timestamp,
+			Subject:   subject,
+		})
+	}
+	return activity, nil
+}
+
+// removeCommit returns commits without the one with the given hash
+func removeCommit(commits []LedgerCommit, hash string) []LedgerCommit {
+	for i, commit := range commits {
+		if commit.Hash == hash {
+			return append(commits[:i], commits[i+1:]...)
+		}
+	}
+	return commits
+}
+
+// HeadAt returns what was checked out at a millisecond timestamp
+func (a *GitActivity) HeadAt(ts int64) GitHead {
+	if len(a.changes) == 0 {
+		return a.Start
+	}
+	i := sort.Search(len(a.changes), func(i int) bool { return a.changes[i].Timestamp > ts })
+	if i == 0 {
+		return a.before
+	}
+	return a.changes[i-1].Head
+}
+
+// commitAfter returns the hash of the first commit created at or after a
+// millisecond timestamp, which shipped the work of a request made then, or ""
+// when none was. Commit times have second precision, so a commit in the same
+// second as the request is taken to follow it.
+func (a *GitActivity) commitAfter(ts int64) string {
+	for _, commit := range a.Commits {
+		if commit.Timestamp >= ts/1000*1000 {
+			return commit.Hash
+		}
+	}
+	return ""
+}
+
+// Apply records the branch and commit of the task and of each of its API
+// requests in a ledger, and the cost of the requests each commit shipped
+func (a *GitActivity) Apply(ledger *Ledger) {
+	ledger.Task.StartBranch = a.Start.Branch
+	ledger.Task.StartCommit = a.Start.Commit
+	ledger.Task.EndBranch = a.End.Branch
+	ledger.Task.EndCommit = a.End.Commit
+
+	commits := make([]LedgerCommit, len(a.Commits))
+	index := make(map[string]int)
+	for i, commit := range a.Commits {
+		commit.ID = ledger.Task.TaskID + "-" + commit.Hash
+		commit.TaskID = ledger.Task.TaskID
+		commits[i] = commit
+		index[commit.Hash] = i
+	}
+	for i := range ledger.APIRequests {
+		req := &ledger.APIRequests[i]
+		req.Branch = a.HeadAt(req.Timestamp).Branch
+		req.Commit = a.commitAfter(req.Timestamp)
+		if j, ok := index[req.Commit]; ok {
+			commits[j].Requests++
+			commits[j].Cost += req.Cost
+		}
+	}
+	ledger.Commits = commits
+}
+
+// ApplyRecords fills in the branch checked out when each record's message was
+// sent and the commit that shipped it. Records match messages one to one.
+func (a *GitActivity) ApplyRecords(records []CostRecord, messages []UIMessage) {
+	for i := range records {
+		if i >= len(messages) {
+			break
+		}
+		records[i].Branch = a.HeadAt(messages[i].Timestamp).Branch
+		records[i].Commit = a.commitAfter(messages[i].Timestamp)
+	}
+}
+
+// BranchCost is the cost of the API requests made on one branch
+type BranchCost struct {
+	Branch   string
+	Requests int
+	Cost     float64
+}
+
+// CostByBranch totals the cost of a ledger's API requests per branch they
+// were made on, most expensive first. Requests made with a detached HEAD are
+// grouped together. It returns nil for tasks outside git.
+func CostByBranch(ledger *Ledger) []BranchCost {
+	if ledger.Task.EndBranch == "" && ledger.Task.EndCommit == "" && ledger.Task.StartCommit == "" {
+		return nil
+	}
+	costs := make(map[string]*BranchCost)
+	var order []string
+	for _, req := range ledger.APIRequests {
+		branch := req.Branch
+		if branch == "" {
+			branch = detachedBranch
+		}
+		cost, ok := costs[branch]
+		if !ok {
+			cost = &BranchCost{Branch: branch}
+			costs[branch] = cost
+			order = append(order, branch)
+		}
+		cost.Requests++
+		cost.Cost += req.Cost
+	}
+	result := make([]BranchCost, 0, len(order))
+	for _, branch := range order {
+		result = append(result, *costs[branch])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Cost > result[j].Cost
+	})
+	return result
+}
+
+// UncommittedCost is the cost of a ledger's API requests made after its last
+// commit, whose work is not in any commit created during the task
+func UncommittedCost(ledger *Ledger) float64 {
+	var cost float64
+	for _, req := range ledger.APIRequests {
+		if req.Commit == "" {
+			cost += req.Cost
+		}
+	}
+	return cost
+}
+
+// ShortHash abbreviates a commit hash as git log --oneline does
+func ShortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// readGitHead reads HEAD from a git directory and resolves the branch it
+// points at through the loose and packed refs of the repository
+func readGitHead(dotGit string) (GitHead, error) {
+	data, err := os.ReadFile(filepath.Join(dotGit, "HEAD"))
+	if err != nil {
+		return GitHead{}, err
+	}
+	content := strings.TrimSpace(string(data))
+	ref, ok := strings.CutPrefix(content, "ref:")
+	if !ok {
+		if !commitHash.MatchString(content) {
+			return GitHead{}, fmt.Errorf("unexpected HEAD %q", content)
+		}
+		return GitHead{Commit: content}, nil
+	}
+	ref = strings.TrimSpace(ref)
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok || branch == ".invalid" {
+		// The refs of a reftable repository are not files
+		return GitHead{}, fmt.Errorf("unsupported HEAD %q", content)
+	}
+	commit, err := resolveRef(dotGit, ref)
+	if err != nil {
+		return GitHead{}, err
+	}
+	return GitHead{Branch: branch, Commit: commit}, nil
+}
+
+// resolveRef returns the commit a ref points at, or "" for a branch without
+// commits
+func resolveRef(dotGit, ref string) (string, error) {
+	common := gitCommonDir(dotGit)
+	for _, dir := range []string{dotGit, common} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+
+	file, err := os.Open(filepath.Join(common, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref {
+			return hash, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// readHeadReflog reads the moves of HEAD from the reflog of a git directory,
+// oldest first, and returns HEAD before the first of them. The branch of each
+// entry is found by walking back from head through the checkouts.
+func readHeadReflog(dotGit string, head GitHead) ([]headChange, GitHead, error) {
+	file, err := os.Open(filepath.Join(dotGit, "logs", "HEAD"))
+	if os.IsNotExist(err) {
+		return nil, head, nil
+	}
+	if err != nil {
+		return nil, head, err
+	}
+	defer file.Close()
+
+	var changes []headChange
+	var first string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// <old> <new> <name> <<email>> <seconds> <zone>\t<message>
+		header, message, _ := strings.Cut(scanner.Text(), "\t")
+		fields := strings.Fields(header)
+		if len(fields) < 4 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(changes) == 0 {
+			first = fields[0]
+		}
+		changes = append(changes, headChange{
+			Timestamp: seconds * 1000,
+			From:      fields[0],
+			Head:      GitHead{Commit: fields[1]},
+			Message:   message,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, head, err
+	}
+
+	branch := head.Branch
+	for i := len(changes) - 1; i >= 0; i-- {
+		changes[i].Head.Branch = branch
+		if move, ok := strings.CutPrefix(changes[i].Message, "checkout: moving from "); ok {
+			from, _, _ := strings.Cut(move, " to ")
+			branch = from
+			if commitHash.MatchString(from) {
+				branch = ""
+			}
+		}
+	}
+	before := GitHead{Branch: branch}
+	if strings.Trim(first, "0") != "" {
+		before.Commit = first
+	}
+	return changes, before, nil
+}
+
+// createdCommit reports whether a reflog message records a new commit, and
+// returns its subject
+func createdCommit(message string) (string, bool) {
+	action, subject, _ := strings.Cut(message, ": ")
+	switch {
+	case strings.HasPrefix(action, "commit"), action == "cherry-pick", action == "revert":
+		return subject, true
+	case strings.HasPrefix(action, "merge ") && !strings.HasPrefix(subject, "Fast-forward"):
+		return message, true
+	}
+	return "", false
+}
+
+// gitHeadFromCLI asks the local git what is checked out at root
+func gitHeadFromCLI(root string) (GitHead, error) {
+	var head GitHead
+	// rev-parse fails on a branch without commits
+	if out, err := git(root, "rev-parse", "--verify", "-q", "HEAD"); err == nil {
+		head.Commit = strings.TrimSpace(out)
+	}
+	// symbolic-ref fails when HEAD is detached
+	if out, err := git(root, "symbolic-ref", "-q", "--short", "HEAD"); err == nil {
+		head.Branch = strings.TrimSpace(out)
+	}
+	if head.Commit == "" && head.Branch == "" {
+		return head, fmt.Errorf("failed to read HEAD of %s", root)
+	}
+	return head, nil
+}
+
+// gitCommitsFromCLI asks the local git for the commits reachable from HEAD
+// that the configured user committed between two millisecond timestamps,
+// oldest first. Which branch each was made on is unknown, so they are given
+// branch.
+func gitCommitsFromCLI(root, branch string, start, end int64) ([]LedgerCommit, error) {
+	// Git timestamps have second precision
+	start = start / 1000 * 1000
+	args := []string{"log", "HEAD", "--reverse", "--format=%H%x1f%ct%x1f%s",
+		fmt.Sprintf("--since=@%d", start/1000), fmt.Sprintf("--until=@%d", (end+999)/1000)}
+	if author := gitUser(root); author != "" {
+		args = append(args, "--author="+regexp.QuoteMeta(author))
+	}
+	out, err := git(root, args...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []LedgerCommit
+	for _, line := range nonEmptyLines(out) {
+		fields := strings.SplitN(line, "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || seconds*1000 < start || seconds*1000 > end {
+			continue
+		}
+		commits = append(commits, LedgerCommit{
+			Hash:      fields[0],
+			Branch:    branch,
+			Timestamp: seconds * 1000,
+			Subject:   fields[2],
+		})
+	}
+	return commits, nil
+}
+
+// gitUser returns the email, or else the name, of the user configured to
+// commit at root, or "" when neither is set
+func gitUser(root string) string {
+	for _, key := range []string{"user.email", "user.name"} {
+		if out, err := git(root, "config", key); err == nil && strings.TrimSpace(out) != "" {
+			return strings.TrimSpace(out)
+		}
+	}
+	return ""
+}
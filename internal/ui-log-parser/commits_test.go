@@ -0,0 +1,201 @@
+package uilogparser
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreatedCommit(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+		wantOK  bool
+	}{
+		{"commit: add parser", "add parser", true},
+		{"commit (initial): first", "first", true},
+		{"commit (amend): add parser", "add parser", true},
+		{"commit (merge): Merge branch 'x'", "Merge branch 'x'", true},
+		{"cherry-pick: fix typo", "fix typo", true},
+		{"revert: Revert \"fix\"", "Revert \"fix\"", true},
+		{"merge feature: Merge made by the 'ort' strategy.", "merge feature: Merge made by the 'ort' strategy.", true},
+		{"merge feature: Fast-forward", "", false},
+		{"checkout: moving from main to feature", "", false},
+		{"reset: moving to HEAD~1", "", false},
+		{"pull: Fast-forward", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			got, ok := createdCommit(tt.message)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("createdCommit() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// hash returns a full commit hash made of c
+func hash(c string) string {
+	return strings.Repeat(c, 40)
+}
+
+// reflogLine returns a HEAD reflog entry moving from one commit to another
+func reflogLine(from, to string, seconds int64, message string) string {
+	return fmt.Sprintf("%s %s Dev <dev@example.com> %d +0000\t%s\n", from, to, seconds, message)
+}
+
+func TestReadGitActivityFromReflog(t *testing.T) {
+	initial := reflogLine(hash("0"), hash("a"), 1000, "commit (initial): init")
+	tests := []struct {
+		name       string
+		reflog     string
+		head       string
+		start, end int64
+		want       []string
+		wantStart  GitHead
+		// wantFirst is the commit shipping the task's first request
+		wantFirst string
+	}{
+		{
+			"commit in the second of the first message",
+			initial + reflogLine(hash("a"), hash("b"), 2000, "commit: add parser"),
+			hash("b"), 2000400, 3000000,
+			[]string{"b add parser"},
+			GitHead{Branch: "main", Commit: hash("a")},
+			hash("b"),
+		},
+		{
+			"amend replaces the commit",
+			initial + reflogLine(hash("a"), hash("b"), 2000, "commit: add parser") +
+				reflogLine(hash("b"), hash("c"), 2100, "commit (amend): add the parser"),
+			hash("c"), 1500000, 3000000,
+			[]string{"c add the parser"},
+			GitHead{Branch: "main", Commit: hash("a")},
+			hash("c"),
+		},
+		{
+			"amend of a commit made before the task",
+			initial + reflogLine(hash("a"), hash("c"), 2100, "commit (amend): init again"),
+			hash("c"), 1500000, 3000000,
+			[]string{"c init again"},
+			GitHead{Branch: "main", Commit: hash("a")},
+			hash("c"),
+		},
+		{
+			"commits on two branches",
+			initial + reflogLine(hash("a"), hash("a"), 1600, "checkout: moving from main to feature") +
+				reflogLine(hash("a"), hash("b"), 2000, "commit: add parser") +
+				reflogLine(hash("b"), hash("a"), 2100, "checkout: moving from feature to main") +
+				reflogLine(hash("a"), hash("c"), 2200, "commit: fix docs"),
+			hash("c"), 1500000, 3000000,
+			[]string{"b add parser", "c fix docs"},
+			GitHead{Branch: "main", Commit: hash("a")},
+			hash("b"),
+		},
+		{
+			"commit after the task",
+			initial + reflogLine(hash("a"), hash("b"), 4000, "commit: later"),
+			hash("b"), 1500000, 3000000,
+			nil,
+			GitHead{Branch: "main", Commit: hash("a")},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			mkfile(t, root, ".git/HEAD", "ref: refs/heads/main\n")
+			mkfile(t, root, ".git/refs/heads/main", tt.head+"\n")
+			mkfile(t, root, ".git/logs/HEAD", tt.reflog)
+
+			activity, err := ReadGitActivity(root, tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, commit := range activity.Commits {
+				got = append(got, commit.Hash[:1]+" "+commit.Subject)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commits = %q, want %q", got, tt.want)
+			}
+			if activity.Start != tt.wantStart {
+				t.Errorf("start = %+v, want %+v", activity.Start, tt.wantStart)
+			}
+			if got := activity.commitAfter(tt.start); got != tt.wantFirst {
+				t.Errorf("first request shipped in %q, want %q", got, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestReadHeadReflogBranches(t *testing.T) {
+	dotGit := filepath.Join(t.TempDir(), ".git")
+	mkfile(t, dotGit, "logs/HEAD", reflogLine(hash("0"), hash("a"), 1000, "commit (initial): init")+
+		reflogLine(hash("a"), hash("a"), 1100, "checkout: moving from main to feature")+
+		reflogLine(hash("a"), hash("b"), 1200, "commit: add parser")+
+		reflogLine(hash("b"), hash("b"), 1300, "checkout: moving from feature to "+hash("b")))
+
+	changes, before, err := readHeadReflog(dotGit, GitHead{Commit: hash("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var branches []string
+	for _, change := range changes {
+		branches = append(branches, change.Head.Branch)
+	}
+	if want := []string{"main", "feature", "feature", ""}; !reflect.DeepEqual(branches, want) {
+		t.Errorf("branches = %q, want %q", branches, want)
+	}
+	if before != (GitHead{Branch: "main"}) {
+		t.Errorf("before = %+v, want main without a commit", before)
+	}
+
+	if changes, before, err := readHeadReflog(filepath.Join(t.TempDir(), ".git"), GitHead{Branch: "main"}); err != nil || changes != nil || before.Branch != "main" {
+		t.Errorf("without a reflog got %v, %+v, %v", changes, before, err)
+	}
+}
+
+func TestGitCommitsFromCLI(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	run := func(seconds int64, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+		// git reads small epoch timestamps as other date formats
+		date := fmt.Sprintf("@%d +0000", 1700000000+seconds)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date, "GIT_CONFIG_NOSYSTEM=1", "HOME="+root)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run(0, "init", "-q", "-b", "main")
+	run(0, "config", "user.email", "dev@example.com")
+	run(0, "config", "user.name", "Dev")
+	run(1000, "commit", "-q", "--allow-empty", "-m", "before the task")
+	run(2000, "commit", "-q", "--allow-empty", "-m", "in the first second")
+	run(2100, "commit", "-q", "--allow-empty", "--author", "Other <other@example.com>", "-m", "by someone else")
+	run(2200, "checkout", "-q", "-b", "side")
+	run(2300, "commit", "-q", "--allow-empty", "-m", "on another branch")
+	run(2400, "checkout", "-q", "main")
+	run(2500, "commit", "-q", "--allow-empty", "-m", "fix docs")
+
+	base := int64(1700000000 * 1000)
+	commits, err := gitCommitsFromCLI(root, "main", base+2000400, base+3000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, commit := range commits {
+		got = append(got, commit.Subject)
+	}
+	if want := []string{"in the first second", "fix docs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commits = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package uilogparser
+
+// AnalyzeCompletion finds completion_result messages and user feedback that
+// reopens the task after one, and splits the task cost into the cost to reach
+// the first completion and the rework done after it
+func AnalyzeCompletion(messages []UIMessage) CompletionStats {
+	var stats CompletionStats
+	completed := false
+
+	for _, msg := range messages {
+		if req, ok := ParseAPIRequest(msg); ok {
+			if stats.Completions == 0 {
+				stats.CostToFirstCompletion += req.Cost
+			} else {
+				stats.ReworkCost += req.Cost
+			}
+			continue
+		}
+
+		if msg.Type != "say" {
+			continue
+		}
+
+		switch msg.Say {
+		case "completion_result":
+			// Cline can write the same result more than once while streaming
+			if completed {
+				continue
+			}
+			completed = true
+			stats.Completions++
+			if stats.FirstCompletion == 0 {
+				stats.FirstCompletion = msg.Timestamp
+			}
+		case "user_feedback":
+			if completed {
+				stats.Reopens++
+				completed = false
+			}
+		}
+	}
+
+	return stats
+}
+
+// ReworkRatio returns the share of the task cost spent after the first completion
+func (s CompletionStats) ReworkRatio() float64 {
+	total := s.CostToFirstCompletion + s.ReworkCost
+	if total == 0 {
+		return 0
+	}
+	return s.ReworkCost / total
+}
@@ -0,0 +1,66 @@
+package uilogparser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyzeCompletion(t *testing.T) {
+	completion := UIMessage{Type: "say", Say: "completion_result", Text: "done", Timestamp: 2000}
+	feedback := UIMessage{Type: "say", Say: "user_feedback", Text: "not quite"}
+	tests := []struct {
+		name     string
+		messages []UIMessage
+		want     CompletionStats
+	}{
+		{
+			"never completed",
+			[]UIMessage{feedback, requestMessage("work", 0.5)},
+			CompletionStats{CostToFirstCompletion: 0.5},
+		},
+		{
+			"completed once",
+			[]UIMessage{requestMessage("work", 0.5), completion, completion},
+			CompletionStats{Completions: 1, FirstCompletion: 2000, CostToFirstCompletion: 0.5},
+		},
+		{
+			"reopened twice",
+			[]UIMessage{
+				requestMessage("work", 0.5), completion,
+				feedback, feedback, requestMessage("fix", 0.2), completion,
+				feedback, requestMessage("fix again", 0.3), completion,
+			},
+			CompletionStats{Completions: 3, Reopens: 2, FirstCompletion: 2000, CostToFirstCompletion: 0.5, ReworkCost: 0.5},
+		},
+		{
+			"feedback before the first completion",
+			[]UIMessage{requestMessage("work", 0.5), feedback, requestMessage("more", 0.25), completion},
+			CompletionStats{Completions: 1, FirstCompletion: 2000, CostToFirstCompletion: 0.75},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AnalyzeCompletion(tt.messages)
+			if got.Completions != tt.want.Completions || got.Reopens != tt.want.Reopens || got.FirstCompletion != tt.want.FirstCompletion ||
+				math.Abs(got.CostToFirstCompletion-tt.want.CostToFirstCompletion) > 1e-9 || math.Abs(got.ReworkCost-tt.want.ReworkCost) > 1e-9 {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReworkRatio(t *testing.T) {
+	tests := []struct {
+		stats CompletionStats
+		want  float64
+	}{
+		{CompletionStats{}, 0},
+		{CompletionStats{CostToFirstCompletion: 1}, 0},
+		{CompletionStats{CostToFirstCompletion: 0.75, ReworkCost: 0.25}, 0.25},
+	}
+	for _, tt := range tests {
+		if got := tt.stats.ReworkRatio(); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v.ReworkRatio() = %v, want %v", tt.stats, got, tt.want)
+		}
+	}
+}
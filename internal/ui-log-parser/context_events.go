@@ -0,0 +1,100 @@
+package uilogparser
+
+// Context event kinds
+const (
+	ContextCondensed = "condense"
+	ContextDropped   = "context_drop"
+)
+
+// contextDropRatio is how far the context has to shrink between two requests,
+// without Cline reporting a condense, to count as a context drop. Cline does
+// not report truncating the conversation, so truncations show up as drops.
+const contextDropRatio = 0.5
+
+// contextDropMinTokens ignores drops from contexts too small to be near the ceiling
+const contextDropMinTokens = 50000
+
+// condenseMessageTypes are the say/ask types Cline and its forks use when the
+// conversation is summarised to free up context
+var condenseMessageTypes = map[string]bool{
+	"condense":         true,
+	"condense_context": true,
+}
+
+// ContextTokens returns the size of the context window used by an API request
+func ContextTokens(req APIRequest) int64 {
+	return req.TokensIn + req.TokensOut + req.CacheWrites + req.CacheReads
+}
+
+// DetectContextEvents finds condense events, plus unexplained drops in context
+// size between consecutive API requests, and records the context size of the
+// requests either side of each event. The cost of a condense event is the cost
+// of the request that produced the summary.
+func DetectContextEvents(messages []UIMessage) []ContextEvent {
+	var events []ContextEvent
+	var pending []int // events waiting for the next request's context size
+
+	var lastTokens int64
+	var lastCost float64
+	haveRequest := false
+
+	for _, msg := range messages {
+		if req, ok := ParseAPIRequest(msg); ok {
+			tokens := ContextTokens(req)
+			// Requests that were cancelled before a response report no usage
+			if tokens == 0 {
+				continue
+			}
+
+			for _, i := range pending {
+				events[i].TokensAfter = tokens
+			}
+
+			if len(pending) == 0 && haveRequest && lastTokens >= contextDropMinTokens && float64(tokens) < float64(lastTokens)*contextDropRatio {
+				events = append(events, ContextEvent{
+					Kind:         ContextDropped,
+					Timestamp:    msg.Timestamp,
+					TokensBefore: lastTokens,
+					TokensAfter:  tokens,
+				})
+			}
+			pending = nil
+
+			lastTokens = tokens
+			lastCost = req.Cost
+			haveRequest = true
+			continue
+		}
+
+		kind := msg.Say
+		if msg.Type == "ask" {
+			kind = msg.Ask
+		}
+
+		if !condenseMessageTypes[kind] {
+			continue
+		}
+		events = append(events, ContextEvent{
+			Kind:         ContextCondensed,
+			Timestamp:    msg.Timestamp,
+			TokensBefore: lastTokens,
+			Cost:         lastCost,
+		})
+		pending = append(pending, len(events)-1)
+	}
+
+	return events
+}
+
+// PeakContextTokens returns the largest context used by any API request
+func PeakContextTokens(messages []UIMessage) int64 {
+	var peak int64
+	for _, msg := range messages {
+		if req, ok := ParseAPIRequest(msg); ok {
+			if tokens := ContextTokens(req); tokens > peak {
+				peak = tokens
+			}
+		}
+	}
+	return peak
+}
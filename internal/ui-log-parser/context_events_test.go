@@ -0,0 +1,38 @@
+package uilogparser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// contextMessage returns an api_req_started message using tokens of context
+func contextMessage(tokens int64) UIMessage {
+	text, _ := json.Marshal(APIRequest{TokensIn: tokens, Cost: 0.1})
+	return UIMessage{Type: "say", Say: "api_req_started", Text: string(text)}
+}
+
+func TestDetectContextEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []UIMessage
+		want     []string
+	}{
+		{"condense", []UIMessage{contextMessage(120000), {Type: "say", Say: "condense_context"}, contextMessage(20000)}, []string{ContextCondensed}},
+		{"unexplained drop", []UIMessage{contextMessage(120000), contextMessage(20000)}, []string{ContextDropped}},
+		{"deleted requests are not a truncation", []UIMessage{contextMessage(30000), {Type: "say", Say: "deleted_api_reqs"}, contextMessage(31000)}, nil},
+		{"small contexts", []UIMessage{contextMessage(40000), contextMessage(1000)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := DetectContextEvents(tt.messages)
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %+v, want kinds %v", events, tt.want)
+			}
+			for i, event := range events {
+				if event.Kind != tt.want[i] || event.TokensAfter == 0 {
+					t.Errorf("event %d is %+v, want kind %s with the context size after", i, event, tt.want[i])
+				}
+			}
+		})
+	}
+}
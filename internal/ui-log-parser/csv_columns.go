@@ -0,0 +1,237 @@
+package uilogparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Built-in CSV column profiles
+const (
+	ProfileMinimal  = "minimal"
+	ProfileStandard = "standard"
+	ProfileForensic = "forensic"
+)
+
+// Text modes control what is written in the Text column
+const (
+	TextFull = "full"
+	TextOmit = "omit"
+	TextHash = "hash"
+)
+
+// Environment variables that configure the CSV columns
+const (
+	ProfileEnvVar       = "COST_TRACKER_CSV_PROFILE"
+	ColumnsEnvVar       = "COST_TRACKER_CSV_COLUMNS"
+	MaxTextLengthEnvVar = "COST_TRACKER_TEXT_MAX"
+	TextModeEnvVar      = "COST_TRACKER_TEXT_MODE"
+)
+
+// csvColumn is a column that can be selected by a profile
+type csvColumn struct {
+	Header string
+	Value  func(record CostRecord) string
+}
+
+// csvColumns maps column names, the snake_case JSON keys of CostRecord, to
+// their CSV header and value
+var csvColumns = map[string]csvColumn{
+	"request_summary":           {"Request Summary", func(r CostRecord) string { return r.RequestSummary }},
+	"ask_say":                   {"Ask/Say", func(r CostRecord) string { return r.AskSay }},
+	"cost":                      {"Cost", func(r CostRecord) string { return r.Cost }},
+	"text":                      {"Text", func(r CostRecord) string { return r.Text }},
+	"timestamp":                 {"Timestamp", func(r CostRecord) string { return r.Timestamp }},
+	"context_tokens":            {"Context tokens used", func(r CostRecord) string { return r.ContextTokens }},
+	"total_cost":                {"Total cost", func(r CostRecord) string { return r.TotalCost }},
+	"cline_action":              {"Cline_Action", func(r CostRecord) string { return r.ClineAction }},
+	"tool_used":                 {"Tool_Used", func(r CostRecord) string { return r.ToolUsed }},
+	"has_images":                {"Has_Images", func(r CostRecord) string { return r.HasImages }},
+	"phase":                     {"Phase", func(r CostRecord) string { return r.Phase }},
+	"context_percentage":        {"Context_Percentage", func(r CostRecord) string { return r.ContextPercentage }},
+	"search_term_in_transcript": {"Search_Term_In_Transcript", func(r CostRecord) string { return r.SearchTermInTranscript }},
+	"cost_notes":                {"Cost_Notes", func(r CostRecord) string { return r.CostNotes }},
+	"time_approx":               {"Time_Approx", func(r CostRecord) string { return r.TimeApprox }},
+	"working_directory":         {"Working_Directory", func(r CostRecord) string { return r.WorkingDirectory }},
+	"branch":                    {"Branch", func(r CostRecord) string { return r.Branch }},
+	"commit":                    {"Commit", func(r CostRecord) string { return r.Commit }},
+	"text_length":               {"Text_Length", func(r CostRecord) string { return strconv.Itoa(len(r.Text)) }},
+	"text_sha256":               {"Text_SHA256", func(r CostRecord) string { return hashText(r.Text) }},
+	"redactions":                {"Redactions", func(r CostRecord) string { return r.Redactions }},
+	"currency":                  {"Currency", func(r CostRecord) string { return r.Currency }},
+	"converted_cost":            {"Converted cost", func(r CostRecord) string { return r.ConvertedCost }},
+	"converted_total_cost":      {"Converted total cost", func(r CostRecord) string { return r.ConvertedTotalCost }},
+}
+
+// ColumnProfile selects and orders the CSV columns and controls how much of
+// each message's text is written
+type ColumnProfile struct {
+	Name    string
+	Columns []string
+	// MaxTextLength truncates the Text column to this many bytes, 0 for no limit
+	MaxTextLength int
+	// TextMode is TextFull, TextOmit to drop the Text column or TextHash to
+	// replace the text with its SHA-256
+	TextMode string
+}
+
+// standardColumns are the columns written before profiles existed, followed
+// by the git branch and commit of each message
+var standardColumns = []string{
+	"request_summary", "ask_say", "cost", "text", "timestamp",
+	"context_tokens", "total_cost", "cline_action",
+	"tool_used", "has_images", "phase", "context_percentage",
+	"search_term_in_transcript", "cost_notes", "time_approx",
+	"working_directory", "branch", "commit",
+}
+
+// columnProfiles are the built-in profiles
+var columnProfiles = map[string]ColumnProfile{
+	ProfileMinimal: {
+		Name:     ProfileMinimal,
+		Columns:  []string{"timestamp", "ask_say", "tool_used", "cost", "total_cost", "context_tokens", "phase"},
+		TextMode: TextOmit,
+	},
+	ProfileStandard: {
+		Name:     ProfileStandard,
+		Columns:  standardColumns,
+		TextMode: TextFull,
+	},
+	ProfileForensic: {
+		Name:     ProfileForensic,
+		Columns:  append(append([]string{}, standardColumns...), "text_length", "text_sha256"),
+		TextMode: TextFull,
+	},
+}
+
+// LookupColumnProfile returns a built-in profile by name, ProfileStandard when
+// the name is empty
+func LookupColumnProfile(name string) (ColumnProfile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = ProfileStandard
+	}
+	profile, ok := columnProfiles[name]
+	if !ok {
+		return ColumnProfile{}, fmt.Errorf("unknown CSV profile: %s", name)
+	}
+	return profile, nil
+}
+
+// ResolveColumnProfile builds the profile selected by opts: the named
+// built-in profile with any column, text length and text mode overrides applied
+func ResolveColumnProfile(opts Options) (ColumnProfile, error) {
+	profile, err := LookupColumnProfile(opts.CSVProfile)
+	if err != nil {
+		return ColumnProfile{}, err
+	}
+	if len(opts.CSVColumns) > 0 {
+		profile.Columns = opts.CSVColumns
+	}
+	if opts.MaxTextLength > 0 {
+		profile.MaxTextLength = opts.MaxTextLength
+	}
+	if opts.TextMode != "" {
+		profile.TextMode = strings.ToLower(strings.TrimSpace(opts.TextMode))
+	}
+
+	switch profile.TextMode {
+	case TextFull, TextOmit, TextHash:
+	default:
+		return ColumnProfile{}, fmt.Errorf("unknown text mode: %s", profile.TextMode)
+	}
+	// Add the converted costs whenever a display currency is set, and record
+	// what was masked whenever redaction is on
+	var extra []string
+	if currency := strings.ToUpper(strings.TrimSpace(opts.Currency)); currency != "" && currency != BaseCurrency {
+		extra = append(extra, "currency", "converted_cost", "converted_total_cost")
+	}
+	if opts.Redact {
+		extra = append(extra, "redactions")
+	}
+	for _, name := range extra {
+		if !containsString(profile.Columns, name) {
+			profile.Columns = append(append([]string{}, profile.Columns...), name)
+		}
+	}
+	for _, name := range profile.Columns {
+		if _, ok := csvColumns[name]; !ok {
+			return ColumnProfile{}, fmt.Errorf("unknown CSV column: %s", name)
+		}
+	}
+	return profile, nil
+}
+
+// names returns the names of the profile's columns in order, without the Text
+// column when text is omitted
+func (p ColumnProfile) names() []string {
+	var names []string
+	for _, name := range p.Columns {
+		if name == "text" && p.TextMode == TextOmit {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// columns returns the profile's columns in the order of names
+func (p ColumnProfile) columns() []csvColumn {
+	var columns []csvColumn
+	for _, name := range p.names() {
+		column := csvColumns[name]
+		if name == "text" {
+			column.Value = p.textValue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// textValue returns a record's text as the profile writes it
+func (p ColumnProfile) textValue(record CostRecord) string {
+	text := record.Text
+	if p.TextMode == TextHash {
+		if text == "" {
+			return ""
+		}
+		return "sha256:" + hashText(text)
+	}
+	return truncateText(text, p.MaxTextLength)
+}
+
+// truncateText cuts text to at most limit bytes on a rune boundary and marks
+// the cut with "...", leaving it unchanged when limit is 0
+func truncateText(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
+
+// ParseColumnList splits a comma separated list of column names
+func ParseColumnList(value string) []string {
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// hashText returns the hex SHA-256 of text, empty for empty text
+func hashText(text string) string {
+	if text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,120 @@
+package uilogparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveColumnProfile(t *testing.T) {
+	standard := []string{
+		"request_summary", "ask_say", "cost", "text", "timestamp",
+		"context_tokens", "total_cost", "cline_action",
+		"tool_used", "has_images", "phase", "context_percentage",
+		"search_term_in_transcript", "cost_notes", "time_approx",
+		"working_directory", "branch", "commit",
+	}
+	tests := []struct {
+		name     string
+		opts     Options
+		want     []string
+		wantMode string
+		wantMax  int
+		wantErr  bool
+	}{
+		{"default is standard", Options{}, standard, TextFull, 0, false},
+		{"standard", Options{CSVProfile: "Standard"}, standard, TextFull, 0, false},
+		{"minimal", Options{CSVProfile: ProfileMinimal}, []string{"timestamp", "ask_say", "tool_used", "cost", "total_cost", "context_tokens", "phase"}, TextOmit, 0, false},
+		{"forensic", Options{CSVProfile: ProfileForensic}, append(append([]string{}, standard...), "text_length", "text_sha256"), TextFull, 0, false},
+		{"columns override", Options{CSVColumns: []string{"cost", "text"}, MaxTextLength: 20, TextMode: "HASH"}, []string{"cost", "text"}, TextHash, 20, false},
+		{"currency and redaction columns", Options{CSVProfile: ProfileMinimal, Currency: "aud", Redact: true}, []string{"timestamp", "ask_say", "tool_used", "cost", "total_cost", "context_tokens", "phase", "currency", "converted_cost", "converted_total_cost", "redactions"}, TextOmit, 0, false},
+		{"USD adds no converted columns", Options{CSVColumns: []string{"cost"}, Currency: "USD"}, []string{"cost"}, TextFull, 0, false},
+		{"unknown profile", Options{CSVProfile: "verbose"}, nil, "", 0, true},
+		{"unknown column", Options{CSVColumns: []string{"cost", "price"}}, nil, "", 0, true},
+		{"unknown text mode", Options{TextMode: "summary"}, nil, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ResolveColumnProfile(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveColumnProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(profile.Columns, tt.want) || profile.TextMode != tt.wantMode || profile.MaxTextLength != tt.wantMax {
+				t.Errorf("got %v %s %d, want %v %s %d", profile.Columns, profile.TextMode, profile.MaxTextLength, tt.want, tt.wantMode, tt.wantMax)
+			}
+		})
+	}
+
+	// Appending the extra columns must not change the built-in profile
+	if _, err := ResolveColumnProfile(Options{Currency: "EUR", Redact: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := columnProfiles[ProfileStandard].Columns; !reflect.DeepEqual(got, standard) {
+		t.Errorf("standard profile changed to %v", got)
+	}
+}
+
+func TestProfileHeaders(t *testing.T) {
+	profile := columnProfiles[ProfileMinimal]
+	var headers []string
+	for _, column := range profile.columns() {
+		headers = append(headers, column.Header)
+	}
+	want := []string{"Timestamp", "Ask/Say", "Tool_Used", "Cost", "Total cost", "Context tokens used", "Phase"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("minimal headers = %q, want %q", headers, want)
+	}
+
+	profile = ColumnProfile{Columns: []string{"cost", "text"}, TextMode: TextOmit}
+	if names := profile.names(); !reflect.DeepEqual(names, []string{"cost"}) {
+		t.Errorf("omitted text still written: %v", names)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"no limit", "hello world", 0, "hello world"},
+		{"negative limit", "hello world", -1, "hello world"},
+		{"within the limit", "hello", 5, "hello"},
+		{"cut", "hello world", 5, "hello..."},
+		{"empty", "", 3, ""},
+		{"inside a multi-byte rune", "héllo", 2, "h..."},
+		{"after a multi-byte rune", "héllo", 3, "hé..."},
+		{"emoji", "ok 👍 done", 5, "ok ..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateText(tt.text, tt.limit); got != tt.want {
+				t.Errorf("truncateText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextValue(t *testing.T) {
+	record := CostRecord{Text: strings.Repeat("a", 12)}
+	tests := []struct {
+		profile ColumnProfile
+		want    string
+	}{
+		{ColumnProfile{TextMode: TextFull}, strings.Repeat("a", 12)},
+		{ColumnProfile{TextMode: TextFull, MaxTextLength: 4}, "aaaa..."},
+		{ColumnProfile{TextMode: TextHash, MaxTextLength: 4}, "sha256:" + hashText(record.Text)},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.textValue(record); got != tt.want {
+			t.Errorf("%s text with max %d = %q, want %q", tt.profile.TextMode, tt.profile.MaxTextLength, got, tt.want)
+		}
+	}
+	if got := (ColumnProfile{TextMode: TextHash}).textValue(CostRecord{}); got != "" {
+		t.Errorf("hash of empty text = %q, want empty", got)
+	}
+}
@@ -6,8 +6,14 @@ import (
 	"path/filepath"
 )
 
-// WriteCSV writes cost records to a CSV file
+// WriteCSV writes cost records to a CSV file with the standard columns
 func WriteCSV(filename string, records []CostRecord) error {
+	return WriteCSVWithProfile(filename, records, columnProfiles[ProfileStandard])
+}
+
+// WriteCSVWithProfile writes cost records to a CSV file with the columns and
+// text handling of a column profile
+func WriteCSVWithProfile(filename string, records []CostRecord, profile ColumnProfile) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -17,13 +23,12 @@ func WriteCSV(filename string, records []CostRecord) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
+	columns := profile.columns()
+
 	// Write header
-	header := []string{
-		"Request Summary", "Ask/Say", "Cost", "Text", "Timestamp",
-		"Context tokens used", "Total cost", "Cline_Action",
-		"Tool_Used", "Has_Images", "Phase", "Context_Percentage",
-		"Search_Term_In_Transcript", "Cost_Notes", "Time_Approx",
-		"Working_Directory",
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
 	}
 	if err := writer.Write(header); err != nil {
 		return err
@@ -31,23 +36,9 @@ func WriteCSV(filename string, records []CostRecord) error {
 
 	// Write records
 	for _, record := range records {
-		row := []string{
-			record.RequestSummary,
-			record.AskSay,
-			record.Cost,
-			record.Text,
-			record.Timestamp,
-			record.ContextTokens,
-			record.TotalCost,
-			record.ClineAction,
-			record.ToolUsed,
-			record.HasImages,
-			record.Phase,
-			record.ContextPercentage,
-			record.SearchTermInTranscript,
-			record.CostNotes,
-			record.TimeApprox,
-			record.WorkingDirectory,
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.Value(record)
 		}
 		if err := writer.Write(row); err != nil {
 			return err
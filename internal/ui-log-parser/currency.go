@@ -0,0 +1,182 @@
+package uilogparser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseCurrency is the currency providers report costs in
+const BaseCurrency = "USD"
+
+// Environment variables that configure currency conversion
+const (
+	CurrencyEnvVar = "COST_TRACKER_CURRENCY"
+	FXRatesEnvVar  = "COST_TRACKER_FX_RATES"
+)
+
+// fxDateLayout is the layout of dates in FX rate files
+const fxDateLayout = "2006-01-02"
+
+// fxRate is the value of one USD in a currency from a date onwards
+type fxRate struct {
+	Date string
+	Rate float64
+}
+
+// RateTable holds dated exchange rates from USD, per currency
+type RateTable struct {
+	rates map[string][]fxRate
+}
+
+// LoadRateTable reads an FX rate file: CSV rows of date (YYYY-MM-DD),
+// currency code and the units of that currency one USD buys on that date. A
+// header row and lines starting with # are skipped.
+func LoadRateTable(path string) (*RateTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FX rate file: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	table := &RateTable{rates: make(map[string][]fxRate)}
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FX rate file: %v", err)
+		}
+		if line == 1 && strings.EqualFold(row[0], "date") {
+			continue
+		}
+
+		if _, err := time.Parse(fxDateLayout, row[0]); err != nil {
+			return nil, fmt.Errorf("invalid date %q in FX rate file", row[0])
+		}
+		rate, err := strconv.ParseFloat(row[2], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %q in FX rate file", row[2])
+		}
+		currency := strings.ToUpper(row[1])
+		table.rates[currency] = append(table.rates[currency], fxRate{Date: row[0], Rate: rate})
+	}
+
+	for _, rates := range table.rates {
+		sort.Slice(rates, func(i, j int) bool { return rates[i].Date < rates[j].Date })
+	}
+	return table, nil
+}
+
+// Rate returns the rate for a currency on a date: the latest rate dated on or
+// before it
+func (t *RateTable) Rate(currency, date string) (float64, error) {
+	rates := t.rates[currency]
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date > date })
+	if i == 0 {
+		return 0, fmt.Errorf("no %s rate on or before %s", currency, date)
+	}
+	return rates[i-1].Rate, nil
+}
+
+// CurrencyConverter converts USD costs into a display currency at the rate for
+// the date each cost was incurred
+type CurrencyConverter struct {
+	Currency string
+	rates    *RateTable
+	loc      *time.Location
+}
+
+// NewCurrencyConverter returns a converter to currency using the rates in
+// ratesPath. Dates are taken in loc. It returns nil for USD, which needs no
+// conversion.
+func NewCurrencyConverter(currency, ratesPath string, loc *time.Location) (*CurrencyConverter, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == BaseCurrency {
+		return nil, nil
+	}
+	if ratesPath == "" {
+		return nil, fmt.Errorf("converting to %s needs an FX rate file, set %s", currency, FXRatesEnvVar)
+	}
+
+	rates, err := LoadRateTable(ratesPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates.rates[currency]) == 0 {
+		return nil, fmt.Errorf("no %s rates in %s", currency, ratesPath)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &CurrencyConverter{Currency: currency, rates: rates, loc: loc}, nil
+}
+
+// Convert converts a USD cost incurred at a millisecond timestamp
+func (c *CurrencyConverter) Convert(usd float64, ts int64) (float64, error) {
+	rate, err := c.rates.Rate(c.Currency, time.UnixMilli(ts).In(c.loc).Format(fxDateLayout))
+	if err != nil {
+		return 0, err
+	}
+	return usd * rate, nil
+}
+
+// ConvertLedger returns the task's total cost converted request by request,
+// leaving out requests without a rate, such as those dated before the first
+// rate of the currency, and the number of requests left out
+func (c *CurrencyConverter) ConvertLedger(ledger *Ledger) (float64, int) {
+	var total float64
+	var skipped int
+	for _, req := range ledger.APIRequests {
+		converted, err := c.Convert(req.Cost, req.Timestamp)
+		if err != nil {
+			skipped++
+			continue
+		}
+		total += converted
+	}
+	return total, skipped
+}
+
+// ConvertRecords fills in the converted cost columns of records, which
+// correspond one to one with messages. The converted columns of records
+// without a rate are left empty and their cost is left out of the running
+// total.
+func (c *CurrencyConverter) ConvertRecords(records []CostRecord, messages []UIMessage) {
+	var total float64
+	for i := range records {
+		if i >= len(messages) {
+			break
+		}
+		records[i].Currency = c.Currency
+		if cost := extractCost(messages[i].Text); cost > 0 {
+			converted, err := c.Convert(cost, messages[i].Timestamp)
+			if err != nil {
+				continue
+			}
+			records[i].ConvertedCost = fmt.Sprintf("%.6f", converted)
+			total += converted
+		}
+		records[i].ConvertedTotalCost = fmt.Sprintf("%.6f", total)
+	}
+}
+
+// FormatMoney formats an amount in the converter's currency, or as USD when
+// the converter is nil
+func (c *CurrencyConverter) FormatMoney(amount float64) string {
+	if c == nil {
+		return fmt.Sprintf("$%.4f", amount)
+	}
+	return fmt.Sprintf("%.4f %s", amount, c.Currency)
+}
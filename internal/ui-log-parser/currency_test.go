@@ -0,0 +1,76 @@
+package uilogparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRates writes an FX rate file and returns its path
+func writeRates(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRateTableRate(t *testing.T) {
+	table, err := LoadRateTable(writeRates(t, "date,currency,rate\n2025-07-01,AUD,1.50\n2025-07-10,AUD,1.55\n2025-07-05,EUR,0.90\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		currency string
+		date     string
+		want     float64
+		wantErr  bool
+	}{
+		{"AUD", "2025-07-01", 1.50, false},
+		{"AUD", "2025-07-09", 1.50, false},
+		{"AUD", "2025-07-10", 1.55, false},
+		{"AUD", "2025-12-31", 1.55, false},
+		{"AUD", "2025-06-30", 0, true},
+		{"EUR", "2025-07-06", 0.90, false},
+		{"GBP", "2025-07-06", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.currency+" "+tt.date, func(t *testing.T) {
+			got, err := table.Rate(tt.currency, tt.date)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Rate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRecordsBeforeFirstRate(t *testing.T) {
+	converter, err := NewCurrencyConverter("aud", writeRates(t, "2025-07-10,AUD,2\n"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Date(2025, 7, 9, 12, 0, 0, 0, time.UTC).UnixMilli()
+	after := time.Date(2025, 7, 10, 12, 0, 0, 0, time.UTC).UnixMilli()
+	messages := []UIMessage{requestMessage("early", 1), requestMessage("late", 0.5)}
+	messages[0].Timestamp, messages[1].Timestamp = before, after
+	records := ProcessMessages(messages)
+
+	converter.ConvertRecords(records, messages[:1])
+	converter.ConvertRecords(records, messages)
+	if records[0].ConvertedCost != "" || records[0].ConvertedTotalCost != "" {
+		t.Errorf("request before the first rate was converted: %+v", records[0])
+	}
+	if records[1].ConvertedCost != "1.000000" || records[1].ConvertedTotalCost != "1.000000" {
+		t.Errorf("got converted cost %q and total %q, want 1.000000", records[1].ConvertedCost, records[1].ConvertedTotalCost)
+	}
+
+	ledger := BuildLedger("1", messages, "")
+	if total, skipped := converter.ConvertLedger(ledger); total != 1 || skipped != 1 {
+		t.Errorf("ConvertLedger() = %v, %d, want 1, 1", total, skipped)
+	}
+}
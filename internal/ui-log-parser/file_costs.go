@@ -0,0 +1,186 @@
+package uilogparser
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// languagesByExtension maps file extensions to the language they are written in
+var languagesByExtension = map[string]string{
+	".go":    "Go",
+	".mod":   "Go",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".cjs":   "JavaScript",
+	".py":    "Python",
+	".rs":    "Rust",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".rb":    "Ruby",
+	".php":   "PHP",
+	".cs":    "C#",
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".hpp":   "C++",
+	".swift": "Swift",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".html":  "HTML",
+	".css":   "CSS",
+	".scss":  "CSS",
+	".md":    "Markdown",
+	".json":  "JSON",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".toml":  "TOML",
+	".xml":   "XML",
+	".csv":   "CSV",
+	".txt":   "Text",
+	".puml":  "PlantUML",
+}
+
+// noExtension labels files without an extension in both the extension and
+// the language rollups, so a file shows up under the same name in each
+const noExtension = "(none)"
+
+// LanguageForPath returns the language of a file based on its extension
+func LanguageForPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return noExtension
+	}
+	if language, ok := languagesByExtension[ext]; ok {
+		return language
+	}
+	return "Other"
+}
+
+// AttributeFileCosts splits the cost of each API request evenly across the
+// files touched by the tool calls the model issued in response to it. Cost of
+// requests that touched no files is returned as unattributed.
+func AttributeFileCosts(messages []UIMessage) ([]FileCost, float64) {
+	costs := make(map[string]*FileCost)
+	var unattributed float64
+
+	var requestCost float64
+	var requestFiles []string
+	inRequest := false
+
+	flush := func() {
+		if !inRequest {
+			return
+		}
+		if len(requestFiles) == 0 {
+			unattributed += requestCost
+			return
+		}
+		share := requestCost / float64(len(requestFiles))
+		for _, path := range requestFiles {
+			costs[path].Requests++
+			costs[path].Cost += share
+		}
+	}
+
+	for _, msg := range messages {
+		if req, ok := ParseAPIRequest(msg); ok {
+			flush()
+			inRequest = true
+			requestCost = req.Cost
+			requestFiles = nil
+			continue
+		}
+
+		call, ok := ParseToolCall(msg)
+		if !ok || !isFileTool(call.Tool) || call.Path == "" {
+			continue
+		}
+
+		path := filepath.Clean(call.Path)
+		fileCost, exists := costs[path]
+		if !exists {
+			fileCost = &FileCost{
+				Path:      path,
+				Extension: strings.ToLower(filepath.Ext(path)),
+				Language:  LanguageForPath(path),
+			}
+			costs[path] = fileCost
+		}
+		fileCost.ToolCalls++
+
+		if inRequest && !containsString(requestFiles, path) {
+			requestFiles = append(requestFiles, path)
+		}
+	}
+	flush()
+
+	result := make([]FileCost, 0, len(costs))
+	for _, fileCost := range costs {
+		result = append(result, *fileCost)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].Path < result[j].Path
+	})
+
+	return result, unattributed
+}
+
+// RollupByExtension groups file costs by file extension
+func RollupByExtension(files []FileCost) []CostRollup {
+	return rollupFileCosts(files, func(f FileCost) string {
+		if f.Extension == "" {
+			return noExtension
+		}
+		return f.Extension
+	})
+}
+
+// RollupByLanguage groups file costs by language
+func RollupByLanguage(files []FileCost) []CostRollup {
+	return rollupFileCosts(files, func(f FileCost) string {
+		return f.Language
+	})
+}
+
+func rollupFileCosts(files []FileCost, key func(FileCost) string) []CostRollup {
+	rollups := make(map[string]*CostRollup)
+	for _, f := range files {
+		k := key(f)
+		rollup, exists := rollups[k]
+		if !exists {
+			rollup = &CostRollup{Key: k}
+			rollups[k] = rollup
+		}
+		rollup.Files++
+		rollup.Cost += f.Cost
+	}
+
+	result := make([]CostRollup, 0, len(rollups))
+	for _, rollup := range rollups {
+		result = append(result, *rollup)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package uilogparser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAttributeFileCosts(t *testing.T) {
+	messages := []UIMessage{
+		toolMessage("readFile", "notes.txt"), // before the first request
+		requestMessage("fix the parser", 0.3),
+		toolMessage("readFile", "main.go"),
+		toolMessage("editedExistingFile", "./main.go"),
+		toolMessage("readFile", "README.md"),
+		requestMessage("look around", 0.2),
+		toolMessage("listFilesTopLevel", "src"),
+		requestMessage("think", 0.5),
+		requestMessage("check", 0.1),
+		toolMessage("readFile", "main.go"),
+	}
+	files, unattributed := AttributeFileCosts(messages)
+
+	want := []FileCost{
+		{Path: "main.go", Extension: ".go", Language: "Go", Requests: 2, ToolCalls: 3, Cost: 0.25},
+		{Path: "src", Extension: "", Language: noExtension, Requests: 1, ToolCalls: 1, Cost: 0.2},
+		{Path: "README.md", Extension: ".md", Language: "Markdown", Requests: 1, ToolCalls: 1, Cost: 0.15},
+		{Path: "notes.txt", Extension: ".txt", Language: "Text", Requests: 0, ToolCalls: 1, Cost: 0},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %+v, want %+v", files, want)
+	}
+	for i, f := range files {
+		w := want[i]
+		if f.Path != w.Path || f.Extension != w.Extension || f.Language != w.Language || f.Requests != w.Requests || f.ToolCalls != w.ToolCalls || math.Abs(f.Cost-w.Cost) > 1e-9 {
+			t.Errorf("file %d = %+v, want %+v", i, f, w)
+		}
+	}
+	if math.Abs(unattributed-0.5) > 1e-9 {
+		t.Errorf("unattributed = %v, want 0.5", unattributed)
+	}
+}
+
+func TestRollupFileCosts(t *testing.T) {
+	files := []FileCost{
+		{Path: "main.go", Extension: ".go", Language: "Go", Cost: 0.25},
+		{Path: "go.mod", Extension: ".mod", Language: "Go", Cost: 0.05},
+		{Path: "Makefile", Extension: "", Language: LanguageForPath("Makefile"), Cost: 0.2},
+		{Path: "README.md", Extension: ".md", Language: "Markdown", Cost: 0.2},
+	}
+	tests := []struct {
+		name   string
+		rollup func([]FileCost) []CostRollup
+		want   []CostRollup
+	}{
+		{"language", RollupByLanguage, []CostRollup{{"Go", 2, 0.3}, {noExtension, 1, 0.2}, {"Markdown", 1, 0.2}}},
+		{"extension", RollupByExtension, []CostRollup{{".go", 1, 0.25}, {noExtension, 1, 0.2}, {".md", 1, 0.2}, {".mod", 1, 0.05}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rollup(files)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Key != tt.want[i].Key || got[i].Files != tt.want[i].Files || math.Abs(got[i].Cost-tt.want[i].Cost) > 1e-9 {
+					t.Errorf("rollup %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLanguageForPath(t *testing.T) {
+	tests := map[string]string{
+		"main.go":         "Go",
+		"web/App.TSX":     "TypeScript",
+		"Makefile":        noExtension,
+		"src":             noExtension,
+		"image.png":       "Other",
+		"docs/design.yml": "YAML",
+	}
+	for path, want := range tests {
+		if got := LanguageForPath(path); got != want {
+			t.Errorf("LanguageForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
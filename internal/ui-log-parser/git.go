@@ -0,0 +1,263 @@
+package uilogparser
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitIgnoreEnvVar is the environment variable that selects where the logs
+// directory is ignored when it is first created in a git repository
+const GitIgnoreEnvVar = "COST_TRACKER_GIT_IGNORE"
+
+// Where the ignore rule of the logs directory is added
+const (
+	// GitIgnoreOff adds no rule
+	GitIgnoreOff = "off"
+	// GitIgnoreExclude adds the rule to .git/info/exclude, which is never
+	// committed
+	GitIgnoreExclude = "exclude"
+	// GitIgnoreFile adds the rule to the .gitignore at the repository root
+	GitIgnoreFile = "gitignore"
+)
+
+// gitIgnoreComment precedes the rules the tracker adds
+const gitIgnoreComment = "# cline-cost-tracker output"
+
+// gitRoot returns the nearest directory at or above dir holding .git, and
+// false when dir is not inside a git repository or is not an absolute path
+// here, such as a Windows path on another system. ResolveRepository also
+// recognises repositories outside git.
+func gitRoot(dir string) (string, bool) {
+	if !filepath.IsAbs(dir) {
+		return dir, false
+	}
+	for current := filepath.Clean(dir); ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir, false
+		}
+		current = parent
+	}
+}
+
+// gitDir returns the git directory of the repository at root. Worktrees and
+// submodules have a .git file pointing at it rather than a directory.
+func gitDir(root string) (string, error) {
+	path := filepath.Join(root, ".git")
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return path, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s is not a gitdir file", path)
+	}
+	dir = strings.TrimSpace(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// gitCommonDir returns the directory holding the files shared by all worktrees
+// of a repository, such as info/exclude, given its git directory
+func gitCommonDir(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "commondir"))
+	if err != nil {
+		return dir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(dir, common)
+	}
+	return filepath.Clean(common)
+}
+
+// validGitIgnore checks a GitIgnore setting
+func validGitIgnore(mode string) error {
+	switch mode {
+	case "", GitIgnoreOff, GitIgnoreExclude, GitIgnoreFile:
+		return nil
+	}
+	return fmt.Errorf("unknown git ignore setting %q, expected %s, %s or %s", mode, GitIgnoreOff, GitIgnoreExclude, GitIgnoreFile)
+}
+
+// CreateLogsDirectory creates the logs directory and, the first time it is
+// created inside a git repository, ignores it there as GitIgnore selects
+func (o Options) CreateLogsDirectory(dir string) error {
+	if err := validGitIgnore(o.GitIgnore); err != nil {
+		return err
+	}
+	_, statErr := os.Stat(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if !os.IsNotExist(statErr) || o.GitIgnore == "" || o.GitIgnore == GitIgnoreOff {
+		return nil
+	}
+	if err := IgnoreLogsDirectory(dir, o.GitIgnore); err != nil {
+		log.Printf("Warning: failed to ignore %s in git: %v", dir, err)
+	}
+	return nil
+}
+
+// IgnoreLogsDirectory adds an ignore rule for a logs directory to the
+// exclude file or .gitignore of the git repository holding it. Directories
+// outside a repository, and rules already present, are left alone.
+func IgnoreLogsDirectory(dir, mode string) error {
+	if err := validGitIgnore(mode); err != nil {
+		return err
+	}
+	root, ok := gitRoot(dir)
+	if !ok {
+		return nil
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || !isWithin(dir, root) {
+		return nil
+	}
+	rule := "/" + filepath.ToSlash(rel) + "/"
+
+	var path string
+	switch mode {
+	case GitIgnoreExclude:
+		dotGit, err := gitDir(root)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(gitCommonDir(dotGit), "info", "exclude")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	case GitIgnoreFile:
+		path = filepath.Join(root, ".gitignore")
+	default:
+		return nil
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.TrimSpace(line) == rule {
+			return nil
+		}
+	}
+
+	var buf bytes.Buffer
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(gitIgnoreComment + "\n" + rule + "\n")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	log.Printf("Ignored %s in %s", rule, path)
+	return nil
+}
+
+// git runs a git command in the repository at root and returns its output
+func git(root string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return string(out), nil
+}
+
+// LogsCheck is what git knows about the logs directory of a repository
+type LogsCheck struct {
+	// Root is the repository root and Directory the logs directory relative
+	// to it
+	Root      string
+	Directory string
+	// Tracked are the files under the logs directory in the index
+	Tracked []string
+	// History are the files under the logs directory in any commit
+	History []string
+	// Commits is the number of commits touching the logs directory
+	Commits int
+	// Ignored reports whether new files in the logs directory are ignored
+	Ignored bool
+}
+
+// CheckLogsInGit asks the local git which files of the logs directory are
+// tracked or in history, and whether the directory is ignored. It returns nil
+// when the directory is not inside a git repository.
+func CheckLogsInGit(logsDir string) (*LogsCheck, error) {
+	root, ok := gitRoot(logsDir)
+	if !ok || !isWithin(logsDir, root) {
+		return nil, nil
+	}
+	rel, err := filepath.Rel(root, logsDir)
+	if err != nil || rel == "." {
+		return nil, nil
+	}
+	check := &LogsCheck{Root: root, Directory: filepath.ToSlash(rel)}
+
+	tracked, err := git(root, "ls-files", "--", check.Directory)
+	if err != nil {
+		return nil, err
+	}
+	check.Tracked = nonEmptyLines(tracked)
+
+	history, err := git(root, "log", "--all", "--format=%x00%H", "--name-only", "--", check.Directory)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, line := range nonEmptyLines(history) {
+		if strings.HasPrefix(line, "\x00") {
+			check.Commits++
+			continue
+		}
+		if !seen[line] {
+			seen[line] = true
+			check.History = append(check.History, line)
+		}
+	}
+
+	// check-ignore exits with 1 when the path is not ignored, and matches
+	// rules whether or not the file exists
+	_, err = git(root, "check-ignore", "-q", "--no-index", check.Directory+"/task_costs.csv")
+	check.Ignored = err == nil
+	return check, nil
+}
+
+// nonEmptyLines splits output into its non-empty lines
+func nonEmptyLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
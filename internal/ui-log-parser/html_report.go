@@ -0,0 +1,386 @@
+package uilogparser
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Chart dimensions in pixels
+const (
+	chartWidth   = 720
+	chartHeight  = 240
+	chartPadding = 48
+)
+
+// htmlMessageTextLimit caps the text shown per message in the message table
+const htmlMessageTextLimit = 2000
+
+// chartSeries is one named line in a line chart
+type chartSeries struct {
+	Name   string
+	Color  string
+	Points [][2]float64
+}
+
+// htmlReportData is the data rendered by htmlReportTemplate
+type htmlReportData struct {
+	Title              string
+	Generated          string
+	Tasks              []LedgerTask
+	TotalCost          float64
+	Currency           string
+	ConvertedTotalCost float64
+	Unconverted        int
+	Charts             []template.HTML
+	Commits            []htmlCommit
+	Messages           []htmlMessage
+}
+
+// HTMLReportOptions controls how an HTML report presents times and costs
+type HTMLReportOptions struct {
+	// Location is the zone timestamps are written in, local when nil
+	Location *time.Location
+	// Converter shows costs in a display currency as well as USD when set
+	Converter *CurrencyConverter
+}
+
+// htmlCommit is one row of the commit table
+type htmlCommit struct {
+	TaskID    string
+	Timestamp string
+	Hash      string
+	Branch    string
+	Subject   string
+	Requests  int
+	Cost      float64
+}
+
+// htmlMessage is one row of the message table
+type htmlMessage struct {
+	TaskID    string
+	Timestamp string
+	Kind      string
+	Cost      string
+	Text      string
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+table { border-collapse: collapse; width: 100%; font-size: 0.85em; }
+th, td { border: 1px solid #ddd; padding: 4px 6px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+td.text { white-space: pre-wrap; word-break: break-word; max-width: 60em; }
+.charts { display: flex; flex-wrap: wrap; gap: 1em; }
+.chart { border: 1px solid #ddd; padding: 0.5em; }
+#search { width: 30em; padding: 4px; margin: 0.5em 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Generated}}. Total cost ${{printf "%.4f" .TotalCost}}{{if .Currency}} ({{printf "%.4f" .ConvertedTotalCost}} {{.Currency}}{{if .Unconverted}}, leaving out {{.Unconverted}} request(s) without a rate{{end}}){{end}} across {{len .Tasks}} task(s).</p>
+
+<h2>Tasks</h2>
+<table>
+<tr><th>Task</th><th>Repository</th><th>Branch</th><th>Cost</th><th>API requests</th><th>Tool calls</th><th>Input tokens</th><th>Output tokens</th><th>Cache reads</th><th>Prompt</th></tr>
+{{range .Tasks}}<tr><td>{{.TaskID}}</td><td>{{.Repository}}</td><td>{{.EndBranch}}</td><td>${{printf "%.4f" .TotalCost}}</td><td>{{.APIRequests}}</td><td>{{.ToolCalls}}</td><td>{{.TokensIn}}</td><td>{{.TokensOut}}</td><td>{{.CacheReads}}</td><td class="text">{{.Prompt}}</td></tr>
+{{end}}</table>
+
+<h2>Charts</h2>
+<div class="charts">
+{{range .Charts}}<div class="chart">{{.}}</div>
+{{end}}</div>
+{{if .Commits}}
+<h2>Commits</h2>
+<table>
+<tr><th>Task</th><th>Time</th><th>Commit</th><th>Branch</th><th>Subject</th><th>API requests</th><th>Attributed cost</th></tr>
+{{range .Commits}}<tr><td>{{.TaskID}}</td><td>{{.Timestamp}}</td><td>{{.Hash}}</td><td>{{.Branch}}</td><td class="text">{{.Subject}}</td><td>{{.Requests}}</td><td>${{printf "%.4f" .Cost}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Messages</h2>
+<input id="search" type="search" placeholder="Search messages..." oninput="filterMessages(this.value)">
+<table id="messages">
+<tr><th>Task</th><th>Time</th><th>Type</th><th>Cost</th><th>Text</th></tr>
+{{range .Messages}}<tr><td>{{.TaskID}}</td><td>{{.Timestamp}}</td><td>{{.Kind}}</td><td>{{.Cost}}</td><td class="text">{{.Text}}</td></tr>
+{{end}}</table>
+
+<script>
+function filterMessages(query) {
+  query = query.toLowerCase();
+  var rows = document.getElementById("messages").rows;
+  for (var i = 1; i < rows.length; i++) {
+    rows[i].style.display = rows[i].textContent.toLowerCase().indexOf(query) === -1 ? "none" : "";
+  }
+}
+</script>
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes a single self-contained HTML dashboard covering the
+// given task ledgers, with inline SVG charts and a searchable message table
+func WriteHTMLReport(filename, title string, ledgers []*Ledger, opts HTMLReportOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return htmlReportTemplate.Execute(file, buildHTMLReportData(title, ledgers, opts))
+}
+
+func buildHTMLReportData(title string, ledgers []*Ledger, opts HTMLReportOptions) htmlReportData {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	data := htmlReportData{
+		Title:     title,
+		Generated: time.Now().In(loc).Format(time.RFC3339),
+	}
+
+	// Requests dated before the first rate keep only their USD cost
+	converter := opts.Converter
+	converted := make(map[string]float64)
+	if converter != nil {
+		for _, ledger := range ledgers {
+			for _, req := range ledger.APIRequests {
+				cost, err := converter.Convert(req.Cost, req.Timestamp)
+				if err != nil {
+					data.Unconverted++
+					continue
+				}
+				converted[req.ID] = cost
+			}
+		}
+		if data.Unconverted > 0 {
+			log.Printf("Warning: %d API requests have no %s rate on or before their date, leaving them unconverted", data.Unconverted, converter.Currency)
+		}
+	}
+	chartCurrency := BaseCurrency
+	if converter != nil {
+		data.Currency = converter.Currency
+		chartCurrency = converter.Currency
+	}
+
+	// Requests across all tasks in time order
+	var requests []LedgerAPIRequest
+	toolCosts := make(map[string]float64)
+	branchCosts := make(map[string]float64)
+	for _, ledger := range ledgers {
+		data.Tasks = append(data.Tasks, ledger.Task)
+		data.TotalCost += ledger.Task.TotalCost
+		requests = append(requests, ledger.APIRequests...)
+		for _, usage := range toolUsage(ledger) {
+			toolCosts[usage.Tool] += usage.Cost
+		}
+		for _, cost := range CostByBranch(ledger) {
+			branchCosts[cost.Branch] += cost.Cost
+		}
+		for _, commit := range ledger.Commits {
+			data.Commits = append(data.Commits, htmlCommit{
+				TaskID:    commit.TaskID,
+				Timestamp: formatTimestamp(commit.Timestamp, loc),
+				Hash:      ShortHash(commit.Hash),
+				Branch:    formatBranch(commit.Branch),
+				Subject:   commit.Subject,
+				Requests:  commit.Requests,
+				Cost:      commit.Cost,
+			})
+		}
+
+		requestCost := make(map[int]LedgerAPIRequest)
+		for _, req := range ledger.APIRequests {
+			requestCost[req.MessageIndex] = req
+		}
+		for _, msg := range ledger.Messages {
+			row := htmlMessage{
+				TaskID:    msg.TaskID,
+				Timestamp: formatTimestamp(msg.Timestamp, loc),
+				Kind:      msg.Type + ": " + msg.Kind,
+				Text:      truncateText(msg.Text, htmlMessageTextLimit),
+			}
+			if req, ok := requestCost[msg.MessageIndex]; ok {
+				row.Cost = fmt.Sprintf("$%.4f", req.Cost)
+				if cost, ok := converted[req.ID]; ok {
+					row.Cost += fmt.Sprintf(" (%.4f %s)", cost, converter.Currency)
+				}
+			}
+			data.Messages = append(data.Messages, row)
+		}
+	}
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Timestamp < requests[j].Timestamp
+	})
+
+	var cumulative, input, output, context [][2]float64
+	var total float64
+	for i, req := range requests {
+		if converter != nil {
+			total += converted[req.ID]
+		} else {
+			total += req.Cost
+		}
+		cumulative = append(cumulative, [2]float64{float64(req.Timestamp), total})
+		input = append(input, [2]float64{float64(i + 1), float64(req.TokensIn + req.CacheWrites + req.CacheReads)})
+		output = append(output, [2]float64{float64(i + 1), float64(req.TokensOut)})
+		context = append(context, [2]float64{float64(i + 1), float64(req.ContextTokens)})
+	}
+
+	data.ConvertedTotalCost = total
+
+	data.Charts = []template.HTML{
+		lineChartSVG("Cumulative cost over time ("+chartCurrency+")", loc, []chartSeries{
+			{Name: "Cost", Color: "#1f77b4", Points: cumulative},
+		}),
+		lineChartSVG("Tokens per request", nil, []chartSeries{
+			{Name: "Input incl. cache", Color: "#2ca02c", Points: input},
+			{Name: "Output", Color: "#d62728", Points: output},
+		}),
+		lineChartSVG("Context growth (tokens)", nil, []chartSeries{
+			{Name: "Context", Color: "#9467bd", Points: context},
+		}),
+		barChartSVG("Cost per tool (USD)", toolCosts),
+	}
+	if len(branchCosts) > 0 {
+		data.Charts = append(data.Charts, barChartSVG("Cost per branch (USD)", branchCosts))
+	}
+
+	return data
+}
+
+// lineChartSVG renders line series as an inline SVG chart. When timeLoc is
+// set the X values are Unix milliseconds labelled in that zone, otherwise
+// request numbers.
+func lineChartSVG(title string, timeLoc *time.Location, series []chartSeries) template.HTML {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="11">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="16" font-weight="bold">%s</text>`, chartPadding, html.EscapeString(title))
+
+	minX, maxX, maxY := 0.0, 1.0, 1.0
+	first := true
+	for _, s := range series {
+		for _, p := range s.Points {
+			if first || p[0] < minX {
+				minX = p[0]
+			}
+			if first || p[0] > maxX {
+				maxX = p[0]
+			}
+			if p[1] > maxY {
+				maxY = p[1]
+			}
+			first = false
+		}
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	scaleX := func(x float64) float64 { return chartPadding + (x-minX)/(maxX-minX)*plotWidth }
+	scaleY := func(y float64) float64 { return chartPadding + plotHeight - y/maxY*plotHeight }
+
+	writeAxes(&b)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, chartPadding-4, chartPadding+4, formatAxisValue(maxY))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">0</text>`, chartPadding-4, chartHeight-chartPadding)
+	if timeLoc != nil && !first {
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, chartPadding, chartHeight-chartPadding+16, formatTimestamp(int64(minX), timeLoc))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, chartWidth-chartPadding, chartHeight-chartPadding+16, formatTimestamp(int64(maxX), timeLoc))
+	} else {
+		fmt.Fprintf(&b, `<text x="%d" y="%d">request %s</text>`, chartPadding, chartHeight-chartPadding+16, formatAxisValue(minX))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">request %s</text>`, chartWidth-chartPadding, chartHeight-chartPadding+16, formatAxisValue(maxX))
+	}
+
+	for i, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		var points []string
+		for _, p := range s.Points {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", scaleX(p[0]), scaleY(p[1])))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, s.Color, strings.Join(points, " "))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s">%s</text>`, chartWidth-chartPadding-120, 16+i*14, s.Color, html.EscapeString(s.Name))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// barChartSVG renders values as an inline SVG horizontal bar chart, largest first
+func barChartSVG(title string, values map[string]float64) template.HTML {
+	labels := make([]string, 0, len(values))
+	maxValue := 0.0
+	for label, value := range values {
+		labels = append(labels, label)
+		if value > maxValue {
+			maxValue = value
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if values[labels[i]] != values[labels[j]] {
+			return values[labels[i]] > values[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+
+	barHeight := 18
+	height := chartPadding + len(labels)*(barHeight+4) + 8
+	if height < chartHeight {
+		height = chartHeight
+	}
+	labelWidth := 160
+	plotWidth := float64(chartWidth - labelWidth - chartPadding - 60)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="11">`, chartWidth, height)
+	fmt.Fprintf(&b, `<text x="%d" y="16" font-weight="bold">%s</text>`, chartPadding, html.EscapeString(title))
+	if len(labels) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d">No tool calls</text>`, chartPadding, chartPadding)
+	}
+	for i, label := range labels {
+		y := chartPadding + i*(barHeight+4)
+		width := 0.0
+		if maxValue > 0 {
+			width = values[label] / maxValue * plotWidth
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, chartPadding+labelWidth-8, y+13, html.EscapeString(label))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="#ff7f0e"/>`, chartPadding+labelWidth, y, width, barHeight)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">$%.4f</text>`, float64(chartPadding+labelWidth)+width+4, y+13, values[label])
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// writeAxes draws the X and Y axes of a line chart
+func writeAxes(b *strings.Builder) {
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, chartPadding, chartPadding, chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, chartPadding, chartHeight-chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+}
+
+// formatAxisValue formats an axis label compactly, e.g. 1.2K or 3.4M
+func formatAxisValue(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fK", v/1e3)
+	case v < 10 && v != float64(int64(v)):
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
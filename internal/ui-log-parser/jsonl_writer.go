@@ -0,0 +1,109 @@
+package uilogparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// jsonColumnTypes are the types of the columns JSON Lines writes as numbers or
+// booleans rather than strings, named as in bundle schemas
+var jsonColumnTypes = map[string]string{
+	"cost":                 "decimal",
+	"total_cost":           "decimal",
+	"converted_cost":       "decimal",
+	"converted_total_cost": "decimal",
+	"context_tokens":       "integer",
+	"context_percentage":   "integer",
+	"text_length":          "integer",
+	"has_images":           "boolean",
+}
+
+// WriteJSONL writes cost records to a JSON Lines file with the standard columns
+func WriteJSONL(filename string, records []CostRecord) error {
+	return WriteJSONLWithProfile(filename, records, columnProfiles[ProfileStandard])
+}
+
+// WriteJSONLWithProfile writes cost records to a JSON Lines file, one object
+// per record with the columns and text handling of a column profile. Costs,
+// token counts and flags are written as JSON numbers and booleans, and null
+// when the record has none.
+func WriteJSONLWithProfile(filename string, records []CostRecord, profile ColumnProfile) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	names := profile.names()
+	columns := profile.columns()
+
+	var line bytes.Buffer
+	for _, record := range records {
+		line.Reset()
+		line.WriteByte('{')
+		for i, column := range columns {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			key, err := marshalJSON(names[i])
+			if err != nil {
+				return err
+			}
+			value, err := marshalJSON(jsonValue(names[i], column.Value(record)))
+			if err != nil {
+				return err
+			}
+			line.Write(key)
+			line.WriteByte(':')
+			line.Write(value)
+		}
+		line.WriteString("}\n")
+		if _, err := writer.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// jsonValue returns the typed value of a column, the text itself for string
+// columns and values that do not parse
+func jsonValue(name, value string) any {
+	kind, typed := jsonColumnTypes[name]
+	if !typed {
+		return value
+	}
+	if value == "" {
+		return nil
+	}
+	switch kind {
+	case "decimal":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "integer":
+		if v, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		return value == "Yes"
+	}
+	return value
+}
+
+// marshalJSON encodes v on one line, keeping transcript text readable, e.g.
+// <task> tags and "&&" in commands
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
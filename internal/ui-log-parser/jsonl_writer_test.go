@@ -0,0 +1,92 @@
+package uilogparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONLWithProfile(t *testing.T) {
+	records := []CostRecord{
+		{AskSay: `"say": "api_req_started"`, Cost: "0.012000", TotalCost: "0.012000", ContextTokens: "1200", ContextPercentage: "45%", HasImages: "No", Text: "first line\nsecond line <task>", Timestamp: "2025-07-28T09:52:01+10:00"},
+		{AskSay: `"say": "text"`, TotalCost: "0.012000", HasImages: "Yes", Text: "ok"},
+	}
+	tests := []struct {
+		name    string
+		profile ColumnProfile
+		want    []map[string]any
+	}{
+		{
+			"typed values",
+			ColumnProfile{Columns: []string{"ask_say", "cost", "total_cost", "context_tokens", "context_percentage", "has_images", "text"}, TextMode: TextFull},
+			[]map[string]any{
+				{"ask_say": `"say": "api_req_started"`, "cost": 0.012, "total_cost": 0.012, "context_tokens": 1200.0, "context_percentage": 45.0, "has_images": false, "text": "first line\nsecond line <task>"},
+				{"ask_say": `"say": "text"`, "cost": nil, "total_cost": 0.012, "context_tokens": nil, "context_percentage": nil, "has_images": true, "text": "ok"},
+			},
+		},
+		{
+			"truncated text",
+			ColumnProfile{Columns: []string{"cost", "text", "text_length"}, TextMode: TextFull, MaxTextLength: 10},
+			[]map[string]any{
+				{"cost": 0.012, "text": "first line...", "text_length": 29.0},
+				{"cost": nil, "text": "ok", "text_length": 2.0},
+			},
+		},
+		{
+			"omitted text",
+			columnProfiles[ProfileMinimal],
+			[]map[string]any{
+				{"timestamp": "2025-07-28T09:52:01+10:00", "ask_say": `"say": "api_req_started"`, "tool_used": "", "cost": 0.012, "total_cost": 0.012, "context_tokens": 1200.0, "phase": ""},
+				{"timestamp": "", "ask_say": `"say": "text"`, "tool_used": "", "cost": nil, "total_cost": 0.012, "context_tokens": nil, "phase": ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "costs.jsonl")
+			if err := WriteJSONLWithProfile(path, records, tt.profile); err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			var lines int
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var got map[string]any
+				if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+					t.Fatalf("line %d is not JSON: %v\n%s", lines+1, err, scanner.Text())
+				}
+				if lines < len(tt.want) && !reflect.DeepEqual(got, tt.want[lines]) {
+					t.Errorf("line %d = %v, want %v", lines+1, got, tt.want[lines])
+				}
+				// Keys follow the profile's column order
+				if first := tt.profile.names()[0]; !strings.HasPrefix(scanner.Text(), `{"`+first+`":`) {
+					t.Errorf("line %d does not start with %s: %s", lines+1, first, scanner.Text())
+				}
+				lines++
+			}
+			if lines != len(tt.want) {
+				t.Errorf("wrote %d lines, want %d", lines, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestNewWriterJSONLProfile(t *testing.T) {
+	writer, err := NewWriter(Options{Format: FormatJSONL, CSVProfile: ProfileForensic, MaxTextLength: 80, TextMode: TextHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := writer.(JSONLWriter).Profile
+	if profile.Name != ProfileForensic || profile.MaxTextLength != 80 || profile.TextMode != TextHash {
+		t.Errorf("JSON Lines writer ignores the text options: %+v", profile)
+	}
+}
@@ -0,0 +1,192 @@
+package uilogparser
+
+import (
+	"fmt"
+)
+
+// SourceCline identifies ledgers built from Cline ui_messages.json files
+const SourceCline = "cline"
+
+// Ledger is the normalized, typed form of a task: one row for the task plus
+// one row per API request, tool call and message, joined by stable IDs
+type Ledger struct {
+	Task        LedgerTask
+	APIRequests []LedgerAPIRequest
+	ToolCalls   []LedgerToolCall
+	Messages    []LedgerMessage
+	Commits     []LedgerCommit
+}
+
+// LedgerTask holds the totals of a task. Repository, RepositoryRoot and
+// Subdirectory are resolved from WorkingDirectory, see ResolveRepository.
+// WorkspaceRoots lists every root of a multi-root workspace, WorkingDirectory
+// first. The branch and commit checked out at the start and end of the task
+// are empty outside git, see ReadGitActivity.
+type LedgerTask struct {
+	TaskID           string
+	Source           string
+	Repository       string
+	WorkingDirectory string
+	RepositoryRoot   string
+	Subdirectory     string
+	WorkspaceRoots   []string
+	CostCentre       string
+	StartBranch      string
+	StartCommit      string
+	EndBranch        string
+	EndCommit        string
+	Prompt           string
+	StartedAt        int64
+	EndedAt          int64
+	TotalCost        float64
+	TokensIn         int64
+	TokensOut        int64
+	CacheWrites      int64
+	CacheReads       int64
+	APIRequests      int
+	ToolCalls        int
+	Messages         int
+	// Redacted is set when the ledger was masked before it was written, and
+	// Redactions counts what each rule masked, e.g. "email:2;token:1"
+	Redacted   bool
+	Redactions string
+}
+
+// LedgerAPIRequest holds the usage and cost of one API request. Branch is the
+// branch checked out when it was made and Commit the first commit created
+// during the task after it, empty when its work was not committed.
+type LedgerAPIRequest struct {
+	ID            string
+	TaskID        string
+	MessageIndex  int
+	Timestamp     int64
+	TokensIn      int64
+	TokensOut     int64
+	CacheWrites   int64
+	CacheReads    int64
+	ContextTokens int64
+	Cost          float64
+	CancelReason  string
+	Branch        string
+	Commit        string
+}
+
+// LedgerToolCall holds one tool call and the API request that issued it
+type LedgerToolCall struct {
+	ID           string
+	TaskID       string
+	RequestID    string
+	MessageIndex int
+	Timestamp    int64
+	Tool         string
+	Path         string
+}
+
+// LedgerCommit holds a commit created while the task ran and the cost of the
+// API requests it shipped, those made since the previous commit
+type LedgerCommit struct {
+	ID        string
+	TaskID    string
+	Hash      string
+	Branch    string
+	Timestamp int64
+	Subject   string
+	Requests  int
+	Cost      float64
+}
+
+// LedgerMessage holds one raw UI message
+type LedgerMessage struct {
+	ID           string
+	TaskID       string
+	MessageIndex int
+	Timestamp    int64
+	Type         string
+	Kind         string
+	Text         string
+}
+
+// LedgerID returns the stable ID of the message at index within a task
+func LedgerID(taskID string, index int) string {
+	return fmt.Sprintf("%s-%d", taskID, index)
+}
+
+// BuildLedger converts the messages of a task into a ledger
+func BuildLedger(taskID string, messages []UIMessage, workingDir string) *Ledger {
+	ledger := &Ledger{
+		Task: LedgerTask{
+			TaskID:           taskID,
+			Source:           SourceCline,
+			WorkingDirectory: workingDir,
+			Messages:         len(messages),
+		},
+	}
+	repo := ResolveRepository(workingDir)
+	ledger.Task.Repository = repo.Name
+	ledger.Task.RepositoryRoot = repo.Root
+	ledger.Task.Subdirectory = repo.Subdirectory
+	if len(messages) > 0 {
+		ledger.Task.Prompt = messages[0].Text
+		ledger.Task.StartedAt = messages[0].Timestamp
+		ledger.Task.EndedAt = messages[len(messages)-1].Timestamp
+	}
+
+	var requestID string
+	for i, msg := range messages {
+		id := LedgerID(taskID, i)
+
+		kind := msg.Say
+		if msg.Type == "ask" {
+			kind = msg.Ask
+		}
+		ledger.Messages = append(ledger.Messages, LedgerMessage{
+			ID:           id,
+			TaskID:       taskID,
+			MessageIndex: i,
+			Timestamp:    msg.Timestamp,
+			Type:         msg.Type,
+			Kind:         kind,
+			Text:         msg.Text,
+		})
+
+		if req, ok := ParseAPIRequest(msg); ok {
+			requestID = id
+			ledger.APIRequests = append(ledger.APIRequests, LedgerAPIRequest{
+				ID:            id,
+				TaskID:        taskID,
+				MessageIndex:  i,
+				Timestamp:     msg.Timestamp,
+				TokensIn:      req.TokensIn,
+				TokensOut:     req.TokensOut,
+				CacheWrites:   req.CacheWrites,
+				CacheReads:    req.CacheReads,
+				ContextTokens: ContextTokens(req),
+				Cost:          req.Cost,
+				CancelReason:  req.CancelReason,
+			})
+			ledger.Task.TotalCost += req.Cost
+			ledger.Task.TokensIn += req.TokensIn
+			ledger.Task.TokensOut += req.TokensOut
+			ledger.Task.CacheWrites += req.CacheWrites
+			ledger.Task.CacheReads += req.CacheReads
+			continue
+		}
+
+		if call, ok := ParseToolCall(msg); ok {
+			ledger.ToolCalls = append(ledger.ToolCalls, LedgerToolCall{
+				ID:           id,
+				TaskID:       taskID,
+				RequestID:    requestID,
+				MessageIndex: i,
+				Timestamp:    msg.Timestamp,
+				Tool:         call.Tool,
+				Path:         call.Path,
+			})
+		}
+	}
+
+	ledger.Task.APIRequests = len(ledger.APIRequests)
+	ledger.Task.ToolCalls = len(ledger.ToolCalls)
+
+	return ledger
+}
@@ -0,0 +1,262 @@
+package uilogparser
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Loop signal kinds
+const (
+	LoopRepeatedToolCall    = "repeated_tool_call"
+	LoopRepeatedError       = "repeated_error"
+	LoopMistakeLimitReached = "mistake_limit_reached"
+	LoopCostWithoutEdits    = "cost_without_edits"
+)
+
+// LoopThresholds controls when the loop detector flags a task
+type LoopThresholds struct {
+	// RepeatedToolCalls is the number of identical tool calls in a row
+	RepeatedToolCalls int
+	// RepeatedErrors is the number of errors in a row, with no turn whose
+	// tool calls all succeeded in between
+	RepeatedErrors int
+	// RequestsWithoutEdits and CostWithoutEdits must both be reached by a run
+	// of API requests that made no file edits
+	RequestsWithoutEdits int
+	CostWithoutEdits     float64
+}
+
+// DefaultLoopThresholds are the thresholds used for reports and the file watcher
+var DefaultLoopThresholds = LoopThresholds{
+	RepeatedToolCalls:    3,
+	RepeatedErrors:       3,
+	RequestsWithoutEdits: 15,
+	CostWithoutEdits:     1.0,
+}
+
+// errorMessageTypes are the say/ask types Cline uses to report failures
+var errorMessageTypes = map[string]bool{
+	"error":          true,
+	"diff_error":     true,
+	"api_req_failed": true,
+}
+
+// DetectLoops watches the message stream for signs that a task is looping:
+// identical tool calls repeated back to back, errors in a row, Cline giving
+// up with mistake_limit_reached, and cost that keeps growing without edits.
+// Loops the task recovered from are returned too; only those still going on
+// at the last message are marked Ongoing.
+func DetectLoops(messages []UIMessage, thresholds LoopThresholds) []LoopSignal {
+	var signals []LoopSignal
+
+	var lastCall string
+	var callRun int
+	var callRunCost float64
+	var errorRun int
+	var errorKinds []string
+	var requestsSinceEdit int
+	var costSinceEdit float64
+	var requestCost float64
+	// turnCalled and turnFailed track the tool calls of the current turn, a
+	// turn whose calls all succeeded ends a run of errors
+	var turnCalled, turnFailed bool
+	// mistakeLimit indexes the last mistake_limit_reached signal while no
+	// API request followed it, -1 otherwise
+	mistakeLimit := -1
+
+	flushCallRun := func(ongoing bool) {
+		if thresholds.RepeatedToolCalls > 0 && callRun >= thresholds.RepeatedToolCalls {
+			signals = append(signals, LoopSignal{
+				Kind:    LoopRepeatedToolCall,
+				Detail:  lastCall,
+				Count:   callRun,
+				Cost:    callRunCost,
+				Ongoing: ongoing,
+			})
+		}
+	}
+
+	flushErrorRun := func(ongoing bool) {
+		if thresholds.RepeatedErrors > 0 && errorRun >= thresholds.RepeatedErrors {
+			signals = append(signals, LoopSignal{
+				Kind:    LoopRepeatedError,
+				Detail:  strings.Join(errorKinds, ", "),
+				Count:   errorRun,
+				Ongoing: ongoing,
+			})
+		}
+		errorRun = 0
+		errorKinds = nil
+	}
+
+	flushEditRun := func(ongoing bool) {
+		if thresholds.RequestsWithoutEdits > 0 && requestsSinceEdit >= thresholds.RequestsWithoutEdits && costSinceEdit >= thresholds.CostWithoutEdits {
+			signals = append(signals, LoopSignal{
+				Kind:    LoopCostWithoutEdits,
+				Detail:  "API requests without a file edit",
+				Count:   requestsSinceEdit,
+				Cost:    costSinceEdit,
+				Ongoing: ongoing,
+			})
+		}
+		requestsSinceEdit = 0
+		costSinceEdit = 0
+	}
+
+	for _, msg := range messages {
+		if req, ok := ParseAPIRequest(msg); ok {
+			if turnCalled && !turnFailed {
+				flushErrorRun(false)
+			}
+			turnCalled, turnFailed = false, false
+			mistakeLimit = -1
+			requestCost = req.Cost
+			requestsSinceEdit++
+			costSinceEdit += req.Cost
+			continue
+		}
+
+		kind := msg.Say
+		if msg.Type == "ask" {
+			kind = msg.Ask
+		}
+
+		if kind == "mistake_limit_reached" {
+			mistakeLimit = len(signals)
+			signals = append(signals, LoopSignal{
+				Kind:   LoopMistakeLimitReached,
+				Detail: truncateDetail(msg.Text),
+				Count:  1,
+			})
+			continue
+		}
+
+		if errorMessageTypes[kind] {
+			turnFailed = true
+			errorRun++
+			if !containsString(errorKinds, kind) {
+				errorKinds = append(errorKinds, kind)
+			}
+			continue
+		}
+
+		signature := toolCallSignature(msg)
+		if signature == "" {
+			continue
+		}
+		turnCalled = true
+
+		if signature == lastCall {
+			callRun++
+			callRunCost += requestCost
+		} else {
+			flushCallRun(false)
+			lastCall = signature
+			callRun = 1
+			callRunCost = requestCost
+		}
+
+		if call, ok := ParseToolCall(msg); ok && (call.Tool == "write_to_file" || call.Tool == "replace_in_file") {
+			flushErrorRun(false)
+			flushEditRun(false)
+		}
+	}
+	flushCallRun(true)
+	flushErrorRun(true)
+	flushEditRun(true)
+	if mistakeLimit >= 0 {
+		signals[mistakeLimit].Ongoing = true
+	}
+
+	return signals
+}
+
+// toolCallSignature identifies a tool call or command so identical calls can
+// be compared. Commands are asked for when they need approval and said when
+// auto-approved.
+func toolCallSignature(msg UIMessage) string {
+	if (msg.Type == "ask" && msg.Ask == "command") || (msg.Type == "say" && msg.Say == "command") {
+		return "execute_command: " + truncateDetail(msg.Text)
+	}
+	call, ok := ParseToolCall(msg)
+	if !ok {
+		return ""
+	}
+	signature := call.Tool
+	if call.Path != "" {
+		signature += " " + filepath.Clean(call.Path)
+	}
+	if call.Regex != "" {
+		signature += " " + call.Regex
+	}
+	if call.Tool == "write_to_file" || call.Tool == "replace_in_file" {
+		signature += fmt.Sprintf(" (%d bytes)", len(call.Content)+len(call.Diff))
+	}
+	return signature
+}
+
+// truncateDetail shortens message text for use in a signal description
+func truncateDetail(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 100 {
+		text = text[:100] + "..."
+	}
+	return text
+}
+
+// FormatLoopSignal renders a loop signal as a single line
+func FormatLoopSignal(signal LoopSignal) string {
+	line := fmt.Sprintf("%s x%d: %s", signal.Kind, signal.Count, signal.Detail)
+	if signal.Cost > 0 {
+		line += fmt.Sprintf(" ($%.6f)", signal.Cost)
+	}
+	if signal.Ongoing {
+		line += " (ongoing)"
+	}
+	return line
+}
+
+// loopSignalKey identifies a loop signal by its kind and what repeats, leaving
+// out the count and cost that grow while the loop goes on
+func loopSignalKey(signal LoopSignal) string {
+	return signal.Kind + ": " + signal.Detail
+}
+
+// LoopWarnings logs a warning when a watched task starts or stops looping.
+// The ongoing signals of each task file are remembered by kind and detail, so
+// a loop is reported once rather than on every write as it grows, and loops
+// the task recovered from are not reported again.
+type LoopWarnings struct {
+	mu   sync.Mutex
+	last map[string]string
+}
+
+// Warn logs the ongoing loop signals of a task report when they differ from
+// those last seen for the task file
+func (w *LoopWarnings) Warn(filePath string, report *TaskReport) {
+	var keys, signals []string
+	for _, signal := range report.OngoingLoops() {
+		keys = append(keys, loopSignalKey(signal))
+		signals = append(signals, FormatLoopSignal(signal))
+	}
+	key := strings.Join(keys, "; ")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.last == nil {
+		w.last = make(map[string]string)
+	}
+	if key == w.last[filePath] {
+		return
+	}
+	w.last[filePath] = key
+
+	if key == "" {
+		log.Printf("Task %s is no longer flagged as looping", report.TaskID)
+		return
+	}
+	log.Printf("WARNING: Task %s looks stuck in a loop (total cost $%.6f): %s", report.TaskID, report.TotalCost, strings.Join(signals, "; "))
+}
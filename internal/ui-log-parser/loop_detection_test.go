@@ -0,0 +1,115 @@
+package uilogparser
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// sayMessage returns a say message of the given type
+func sayMessage(say, text string) UIMessage {
+	return UIMessage{Type: "say", Say: say, Text: text}
+}
+
+func TestDetectLoops(t *testing.T) {
+	read := toolMessage("readFile", "main.go")
+	edit := toolMessage("editedExistingFile", "main.go")
+	request := requestMessage("go on", 0.01)
+	tests := []struct {
+		name     string
+		messages []UIMessage
+		want     []string
+	}{
+		{
+			"repeated reads still going on",
+			[]UIMessage{request, read, request, read, request, read},
+			[]string{"repeated_tool_call x3: read_file main.go ($0.030000) (ongoing)"},
+		},
+		{
+			"recovered from repeated reads",
+			[]UIMessage{request, read, request, read, request, read, request, edit},
+			[]string{"repeated_tool_call x3: read_file main.go ($0.030000)"},
+		},
+		{
+			"auto-approved commands",
+			[]UIMessage{request, sayMessage("command", "go test ./..."), request, sayMessage("command", "go test ./..."), request, sayMessage("command", "go test ./...")},
+			[]string{"repeated_tool_call x3: execute_command: go test ./... ($0.030000) (ongoing)"},
+		},
+		{
+			"errors in a row",
+			[]UIMessage{request, edit, sayMessage("diff_error", ""), request, sayMessage("error", ""), request, sayMessage("error", "")},
+			[]string{"repeated_error x3: diff_error, error (ongoing)"},
+		},
+		{
+			"errors broken by a successful turn",
+			[]UIMessage{request, sayMessage("error", ""), request, sayMessage("error", ""), request, read, request, sayMessage("error", "")},
+			nil,
+		},
+		{
+			"errors around a failing turn",
+			[]UIMessage{request, read, sayMessage("error", ""), request, sayMessage("error", ""), request, sayMessage("error", "")},
+			[]string{"repeated_error x3: error (ongoing)"},
+		},
+		{
+			"mistake limit the user resumed from",
+			[]UIMessage{request, sayMessage("mistake_limit_reached", "help"), request},
+			[]string{"mistake_limit_reached x1: help"},
+		},
+		{
+			"mistake limit at the end",
+			[]UIMessage{request, sayMessage("mistake_limit_reached", "help")},
+			[]string{"mistake_limit_reached x1: help (ongoing)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, signal := range DetectLoops(tt.messages, DefaultLoopThresholds) {
+				got = append(got, FormatLoopSignal(signal))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoopWarningsIgnoresGrowingLoops(t *testing.T) {
+	var out bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&out)
+
+	var messages []UIMessage
+	var warnings LoopWarnings
+	steps := []struct {
+		requests int
+		wantLogs int
+	}{
+		{10, 0}, // below the threshold
+		{15, 1}, // starts looping
+		{16, 1}, // the same loop, costlier
+		{30, 1},
+	}
+	for _, step := range steps {
+		for len(messages) < step.requests {
+			messages = append(messages, requestMessage("more", 0.1))
+		}
+		warnings.Warn("task.json", &TaskReport{TaskID: "1", LoopSignals: DetectLoops(messages, DefaultLoopThresholds)})
+		if got := strings.Count(out.String(), "looks stuck"); got != step.wantLogs {
+			t.Fatalf("after %d requests logged %d warnings, want %d:\n%s", step.requests, got, step.wantLogs, out.String())
+		}
+	}
+
+	// Recovering from the loop is logged once and not warned about again
+	messages = append(messages, toolMessage("editedExistingFile", "main.go"), requestMessage("done", 0.1))
+	for i := 0; i < 2; i++ {
+		warnings.Warn("task.json", &TaskReport{TaskID: "1", LoopSignals: DetectLoops(messages, DefaultLoopThresholds)})
+	}
+	if got := strings.Count(out.String(), "looks stuck"); got != 1 {
+		t.Errorf("a recovered task should not be warned about again, logged %d warnings:\n%s", got, out.String())
+	}
+	if got := strings.Count(out.String(), "no longer flagged"); got != 1 {
+		t.Errorf("recovering should be logged once, logged %d times:\n%s", got, out.String())
+	}
+}
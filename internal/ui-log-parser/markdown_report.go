@@ -0,0 +1,115 @@
+package uilogparser
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// FormatMarkdownReport renders a task report as Markdown, ready to paste into
+// pull request descriptions and retro documents
+func FormatMarkdownReport(report *TaskReport) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Task %s cost report\n\n", report.TaskID)
+
+	if report.Prompt != "" {
+		fmt.Fprintf(&b, "## Prompt\n\n")
+		for _, line := range strings.Split(strings.TrimSpace(report.Prompt), "\n") {
+			fmt.Fprintf(&b, "> %s\n", line)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	fmt.Fprintf(&b, "## Summary\n\n")
+	fmt.Fprintf(&b, "| Metric | Value |\n")
+	fmt.Fprintf(&b, "|--------|-------|\n")
+	fmt.Fprintf(&b, "| Total cost | $%.4f |\n", report.TotalCost)
+	if report.Currency != "" {
+		fmt.Fprintf(&b, "| Total cost in %s | %.4f |\n", report.Currency, report.ConvertedTotalCost)
+	}
+	fmt.Fprintf(&b, "| API requests | %d |\n", report.APIRequests)
+	fmt.Fprintf(&b, "| Started | %s |\n", report.formatTime(report.StartedAt))
+	fmt.Fprintf(&b, "| Duration | %s |\n", report.Duration().Round(time.Second))
+	fmt.Fprintf(&b, "| Input tokens | %d |\n", report.TokensIn)
+	fmt.Fprintf(&b, "| Output tokens | %d |\n", report.TokensOut)
+	fmt.Fprintf(&b, "| Cache writes | %d |\n", report.CacheWrites)
+	fmt.Fprintf(&b, "| Cache reads | %d |\n", report.CacheReads)
+	fmt.Fprintf(&b, "| Cache hit ratio | %.1f%% |\n", report.CacheHitRatio()*100)
+	fmt.Fprintf(&b, "\n")
+
+	fmt.Fprintf(&b, "## Most expensive requests\n\n")
+	if len(report.TopRequests) == 0 {
+		fmt.Fprintf(&b, "_No API requests with a cost._\n\n")
+	} else {
+		fmt.Fprintf(&b, "| # | Time | Cost | Input | Output | Cache reads | Request |\n")
+		fmt.Fprintf(&b, "|---|------|------|-------|--------|-------------|---------|\n")
+		for i, req := range report.TopRequests {
+			fmt.Fprintf(&b, "| %d | %s | $%.4f | %d | %d | %d | %s |\n",
+				i+1, report.formatTime(req.Timestamp), req.Cost, req.TokensIn, req.TokensOut, req.CacheReads, markdownCell(req.Summary))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	fmt.Fprintf(&b, "## Tool usage\n\n")
+	if len(report.ToolUsage) == 0 {
+		fmt.Fprintf(&b, "_No tools used._\n\n")
+	} else {
+		fmt.Fprintf(&b, "| Tool | Calls | Attributed cost |\n")
+		fmt.Fprintf(&b, "|------|-------|-----------------|\n")
+		for _, u := range report.ToolUsage {
+			fmt.Fprintf(&b, "| `%s` | %d | $%.4f |\n", u.Tool, u.Calls, u.Cost)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	if report.Git != nil {
+		fmt.Fprintf(&b, "## Branches and commits\n\n")
+		fmt.Fprintf(&b, "Started on %s, ended on %s.\n\n", formatGitHead(report.Git.Start), formatGitHead(report.Git.End))
+		if len(report.BranchCosts) > 0 {
+			fmt.Fprintf(&b, "| Branch | Requests | Cost |\n")
+			fmt.Fprintf(&b, "|--------|----------|------|\n")
+			for _, c := range report.BranchCosts {
+				fmt.Fprintf(&b, "| `%s` | %d | $%.4f |\n", markdownCell(c.Branch), c.Requests, c.Cost)
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		if len(report.Commits) == 0 {
+			fmt.Fprintf(&b, "_No commits during the task._\n\n")
+		} else {
+			fmt.Fprintf(&b, "| Commit | Branch | Subject | Requests | Attributed cost |\n")
+			fmt.Fprintf(&b, "|--------|--------|---------|----------|-----------------|\n")
+			for _, c := range report.Commits {
+				fmt.Fprintf(&b, "| `%s` | `%s` | %s | %d | $%.4f |\n",
+					ShortHash(c.Hash), markdownCell(formatBranch(c.Branch)), markdownCell(c.Subject), c.Requests, c.Cost)
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		fmt.Fprintf(&b, "Uncommitted work: $%.4f\n\n", report.UncommittedCost)
+	}
+
+	fmt.Fprintf(&b, "## Files touched\n\n")
+	if len(report.FileCosts) == 0 {
+		fmt.Fprintf(&b, "_No files touched._\n")
+	} else {
+		fmt.Fprintf(&b, "| File | Language | Tool calls | Attributed cost |\n")
+		fmt.Fprintf(&b, "|------|----------|------------|-----------------|\n")
+		for _, f := range report.FileCosts {
+			fmt.Fprintf(&b, "| `%s` | %s | %d | $%.4f |\n", markdownCell(f.Path), f.Language, f.ToolCalls, f.Cost)
+		}
+	}
+
+	return b.String()
+}
+
+// WriteMarkdownReport writes a task report as Markdown to a file
+func WriteMarkdownReport(filename string, report *TaskReport) error {
+	return os.WriteFile(filename, []byte(FormatMarkdownReport(report)), 0644)
+}
+
+// markdownCell escapes text for use inside a Markdown table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -0,0 +1,158 @@
+package uilogparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultOutputDir is where records and reports are written, relative to the
+// task's working directory
+const DefaultOutputDir = "ui-log-parser/logs"
+
+// Environment variables that select where and under which names output is
+// written
+const (
+	OutputDirEnvVar      = "COST_TRACKER_OUTPUT_DIR"
+	OutputLocationEnvVar = "COST_TRACKER_OUTPUT_LOCATION"
+	FileNameEnvVar       = "COST_TRACKER_FILE_NAME"
+	ReportsEnvVar        = "COST_TRACKER_REPORTS"
+)
+
+// Output locations
+const (
+	// OutputLocationRepo writes into the task's working directory
+	OutputLocationRepo = "repo"
+	// OutputLocationCentral writes into OutputDir, one directory per repository
+	OutputLocationCentral = "central"
+	// OutputLocationData writes into the data directory, one directory per
+	// repository
+	OutputLocationData = "data"
+)
+
+// DefaultFileName is the file name template of a task's output, to which
+// "_costs" and "_report" are appended
+const DefaultFileName = "task_{task_id}_{started}"
+
+// unknownRepository partitions the output of tasks without a working directory
+const unknownRepository = "unknown"
+
+// partitionHashLength is the number of hex characters of the root's hash in a
+// repository's partition
+const partitionHashLength = 8
+
+// fileNamePlaceholder matches the placeholders of a file name template
+var fileNamePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// DataDirectory returns $XDG_DATA_HOME/cline-cost-tracker, under
+// ~/.local/share when XDG_DATA_HOME is unset, or empty when there is no home
+// directory
+func DataDirectory() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataHome, "cline-cost-tracker")
+}
+
+// LogsDirectory returns the directory a task's records and reports are written
+// to, according to OutputLocation:
+//   - repo, the default: a relative OutputDir, DefaultOutputDir when empty, is
+//     resolved against the root of the repository holding the task's working
+//     directory; an absolute one is used as is. Tasks without a working
+//     directory fall back to the data location.
+//   - central: OutputDir, which must be absolute, partitioned by repository
+//   - data: DataDirectory()/logs, partitioned by repository
+func (o Options) LogsDirectory(workingDir string) (string, error) {
+	repo := ResolveRepository(workingDir)
+	repository := repositoryPartition(repo)
+
+	switch o.OutputLocation {
+	case "", OutputLocationRepo:
+		outputDir := o.OutputDir
+		if outputDir == "" {
+			outputDir = DefaultOutputDir
+		}
+		if filepath.IsAbs(outputDir) {
+			return outputDir, nil
+		}
+		if repo.Root != "" {
+			return filepath.Join(repo.Root, outputDir), nil
+		}
+		log.Printf("Warning: no working directory found, writing to the %s output location", OutputLocationData)
+		fallthrough
+	case OutputLocationData:
+		dataDir := DataDirectory()
+		if dataDir == "" {
+			return "", fmt.Errorf("no data directory: neither XDG_DATA_HOME nor the home directory is set")
+		}
+		return filepath.Join(dataDir, "logs", repository), nil
+	case OutputLocationCentral:
+		if !filepath.IsAbs(o.OutputDir) {
+			return "", fmt.Errorf("output location %s needs an absolute output directory, got %q", OutputLocationCentral, o.OutputDir)
+		}
+		return filepath.Join(o.OutputDir, repository), nil
+	}
+	return "", fmt.Errorf("unknown output location %q, expected %s, %s or %s", o.OutputLocation, OutputLocationRepo, OutputLocationCentral, OutputLocationData)
+}
+
+// repositoryPartition returns the directory of a repository under the central
+// and data locations: its name followed by a short hash of its root, so
+// repositories with the same name in different places are kept apart
+func repositoryPartition(repo Repository) string {
+	if repo.Root == "" {
+		return unknownRepository
+	}
+	sum := sha256.Sum256([]byte(repo.Root))
+	return repo.Name + "-" + hex.EncodeToString(sum[:])[:partitionHashLength]
+}
+
+// OutputName expands the FileName template, DefaultFileName when empty, for a
+// task. Templates may use {task_id}, {started} (the UTC start time),
+// {date} (the UTC start date), {repository} and {source}, and may contain
+// slashes to write into subdirectories of the logs directory.
+func (o Options) OutputName(task LedgerTask) (string, error) {
+	template := o.FileName
+	if template == "" {
+		template = DefaultFileName
+	}
+	repository := task.Repository
+	if repository == "" {
+		repository = unknownRepository
+	}
+	values := map[string]string{
+		"{task_id}":    task.TaskID,
+		"{started}":    formatTimestampForFilename(task.StartedAt),
+		"{date}":       time.UnixMilli(task.StartedAt).UTC().Format("2006-01-02"),
+		"{repository}": repository,
+		"{source}":     task.Source,
+	}
+
+	var unknown []string
+	name := fileNamePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := values[placeholder]
+		if !ok {
+			unknown = append(unknown, placeholder)
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s in file name %q", strings.Join(unknown, ", "), template)
+	}
+
+	name = filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file name %q must stay inside the logs directory", template)
+	}
+	return name, nil
+}
@@ -0,0 +1,91 @@
+package uilogparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputName(t *testing.T) {
+	task := LedgerTask{TaskID: "1753660321000", Source: "cline", Repository: "tracker", StartedAt: 1753660321000}
+	tests := []struct {
+		name     string
+		template string
+		task     LedgerTask
+		want     string
+		wantErr  bool
+	}{
+		{"default", "", task, "task_1753660321000_2025-07-27_23-52-01Z", false},
+		{"all placeholders", "{date}/{repository}-{source}-{task_id}-{started}", task, filepath.FromSlash("2025-07-27/tracker-cline-1753660321000-2025-07-27_23-52-01Z"), false},
+		{"no repository", "{repository}/{task_id}", LedgerTask{TaskID: "1"}, filepath.FromSlash("unknown/1"), false},
+		{"cleaned", "logs/./{task_id}/", task, filepath.FromSlash("logs/1753660321000"), false},
+		{"inner parent stays inside", "a/../{task_id}", task, "1753660321000", false},
+		{"unknown placeholder", "{task_id}_{model}", task, "", true},
+		{"absolute", "/tmp/{task_id}", task, "", true},
+		{"escapes the logs directory", "../{task_id}", task, "", true},
+		{"empty after expansion", "{source}", LedgerTask{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Options{FileName: tt.template}.OutputName(tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OutputName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("OutputName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogsDirectory(t *testing.T) {
+	base := t.TempDir()
+	first := filepath.Join(base, "work", "api")
+	second := filepath.Join(base, "personal", "api")
+	for _, root := range []string{first, second} {
+		if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dataHome := filepath.Join(base, "data")
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	central := filepath.Join(base, "central")
+
+	logsDirectory := func(opts Options, workingDir string) string {
+		t.Helper()
+		dir, err := opts.LogsDirectory(workingDir)
+		if err != nil {
+			t.Fatalf("LogsDirectory(%q) error = %v", workingDir, err)
+		}
+		return dir
+	}
+
+	if got, want := logsDirectory(Options{}, first), filepath.Join(first, DefaultOutputDir); got != want {
+		t.Errorf("repo location = %q, want %q", got, want)
+	}
+
+	for _, opts := range []Options{
+		{OutputLocation: OutputLocationCentral, OutputDir: central},
+		{OutputLocation: OutputLocationData},
+	} {
+		firstDir := logsDirectory(opts, first)
+		secondDir := logsDirectory(opts, second)
+		if firstDir == secondDir {
+			t.Errorf("%s: repositories with the same name share %q", opts.OutputLocation, firstDir)
+		}
+		if !strings.HasPrefix(filepath.Base(firstDir), "api-") || filepath.Base(firstDir) != repositoryPartition(ResolveRepository(first)) {
+			t.Errorf("%s: partition %q, want the name and a hash", opts.OutputLocation, firstDir)
+		}
+		if again := logsDirectory(opts, filepath.Join(first, "cmd")); again != firstDir {
+			t.Errorf("%s: subdirectory partition %q, want %q", opts.OutputLocation, again, firstDir)
+		}
+	}
+
+	if got, want := logsDirectory(Options{}, ""), filepath.Join(dataHome, "cline-cost-tracker", "logs", unknownRepository); got != want {
+		t.Errorf("no working directory = %q, want %q", got, want)
+	}
+	if _, err := (Options{OutputLocation: OutputLocationCentral, OutputDir: "logs"}).LogsDirectory(first); err == nil {
+		t.Error("relative central directory accepted")
+	}
+}
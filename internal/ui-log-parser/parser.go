@@ -22,7 +22,7 @@ func ParseUIMessages(filePath string) ([]UIMessage, error) {
 	}
 
 	fileSizeKB := fileInfo.Size() / 1024
-	fmt.Printf("Processing file: %s (Size: %d KB)\n", filePath, fileSizeKB)
+	log.Printf("Processing file: %s (Size: %d KB)", filePath, fileSizeKB)
 
 	if fileSizeKB > 500 {
 		log.Printf("Warning: Large file detected (%d KB). Processing in chunks to avoid memory issues.", fileSizeKB)
@@ -39,7 +39,7 @@ func ParseUIMessages(filePath string) ([]UIMessage, error) {
 		return nil, fmt.Errorf("error parsing JSON: %v", err)
 	}
 
-	fmt.Printf("Parsed %d messages\n", len(messages))
+	log.Printf("Parsed %d messages", len(messages))
 
 	if len(messages) == 0 {
 		return nil, fmt.Errorf("no messages found in the file")
@@ -55,7 +55,7 @@ func ProcessMessages(messages []UIMessage) []CostRecord {
 
 	for i, msg := range messages {
 		record := CostRecord{
-			Timestamp: formatTimestamp(msg.Timestamp),
+			Timestamp: formatTimestamp(msg.Timestamp, time.Local),
 			Text:      msg.Text,
 		}
 
@@ -89,7 +89,7 @@ func ProcessMessages(messages []UIMessage) []CostRecord {
 		record.ContextPercentage = extractContextPercentage(msg)
 		record.SearchTermInTranscript = generateSearchTerm(msg, i)
 		record.CostNotes = generateCostNotes(msg)
-		record.TimeApprox = formatTimeApprox(msg.Timestamp)
+		record.TimeApprox = formatTimeApprox(msg.Timestamp, time.Local)
 
 		records = append(records, record)
 	}
@@ -97,13 +97,26 @@ func ProcessMessages(messages []UIMessage) []CostRecord {
 	return records
 }
 
-// ExtractTaskID extracts task ID from file path
+// taskIDPattern matches task IDs: a millisecond timestamp for Cline, a UUID
+// for Roo Code, Kilo Code and Claude Code sessions
+const taskIDPattern = `[0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+
+// ExtractTaskID extracts task ID from file path: the task directory, whatever
+// the configured tasks root is called, or the session transcript's name
 func ExtractTaskID(path string) string {
-	re := regexp.MustCompile(`tasks/([0-9]+)/`)
+	re := regexp.MustCompile(`tasks/(` + taskIDPattern + `)/`)
 	matches := re.FindStringSubmatch(path)
 	if len(matches) > 1 {
 		return matches[1]
 	}
+	taskIDRe := regexp.MustCompile(`^(` + taskIDPattern + `)$`)
+	if dir := filepath.Base(filepath.Dir(path)); taskIDRe.MatchString(dir) {
+		return dir
+	}
+	// Claude Code names each session transcript after its session ID
+	if name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)); taskIDRe.MatchString(name) {
+		return name
+	}
 	return "unknown"
 }
 
@@ -241,87 +254,36 @@ func generateCostNotes(msg UIMessage) string {
 	return ""
 }
 
-func formatTimestamp(ts int64) string {
-	t := time.Unix(ts/1000, (ts%1000)*1000000)
-	return t.Format("2006-01-02 15:04:05")
+// formatTimestamp formats a millisecond timestamp as RFC 3339 in loc, with an
+// explicit offset
+func formatTimestamp(ts int64, loc *time.Location) string {
+	return time.UnixMilli(ts).In(loc).Format(time.RFC3339)
 }
 
+// formatTimestampForFilename formats a millisecond timestamp for file names.
+// It always uses UTC so the same task gets the same file name on every machine.
 func formatTimestampForFilename(ts int64) string {
-	t := time.Unix(ts/1000, (ts%1000)*1000000)
-	return t.Format("2006-01-02_15-04-05")
-}
-
-func formatTimeApprox(ts int64) string {
-	t := time.Unix(ts/1000, (ts%1000)*1000000)
-	return t.Format("15:04")
+	return time.UnixMilli(ts).UTC().Format("2006-01-02_15-04-05Z")
 }
 
-// extractWorkingDirectoryFromFile extracts the working directory from ui_messages.json
-func extractWorkingDirectoryFromFile(filePath string) string {
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Printf("DEBUG: Error opening file for working directory extraction: %v", err)
-		return ""
-	}
-	defer file.Close()
-
-	// Read the file content
-	content := make([]byte, 10240) // Read first 10KB to find the working directory
-	n, err := file.Read(content)
-	if err != nil && n == 0 {
-		log.Printf("DEBUG: Error reading file for working directory extraction: %v", err)
-		return ""
-	}
-
-	contentStr := string(content[:n])
-
-	// Look for the pattern "# Current Working Directory (/path/to/directory)"
-	workingDirPattern := "# Current Working Directory ("
-	startIdx := strings.Index(contentStr, workingDirPattern)
-	if startIdx == -1 {
-		log.Printf("DEBUG: Working directory pattern not found in file")
-		return ""
-	}
-
-	// Find the start of the path
-	pathStart := startIdx + len(workingDirPattern)
-
-	// Find the end of the path (closing parenthesis)
-	pathEnd := strings.Index(contentStr[pathStart:], ")")
-	if pathEnd == -1 {
-		log.Printf("DEBUG: Could not find end of working directory path in file")
-		return ""
-	}
-
-	workingDir := contentStr[pathStart : pathStart+pathEnd]
-	log.Printf("DEBUG: Extracted working directory from file: %s", workingDir)
-	return workingDir
+// formatTimeApprox formats the time of day of a millisecond timestamp in loc
+func formatTimeApprox(ts int64, loc *time.Location) string {
+	return time.UnixMilli(ts).In(loc).Format("15:04")
 }
 
 // extractWorkingDirectoryFromMessage extracts working directory from individual message text
 func extractWorkingDirectoryFromMessage(text string) string {
-	// Look for the pattern "# Current Working Directory (/path/to/directory)"
-	workingDirPattern := "# Current Working Directory ("
-	startIdx := strings.Index(text, workingDirPattern)
-	if startIdx == -1 {
-		return ""
-	}
-
-	// Find the start of the path
-	pathStart := startIdx + len(workingDirPattern)
-
-	// Find the end of the path (closing parenthesis)
-	pathEnd := strings.Index(text[pathStart:], ")")
-	if pathEnd == -1 {
-		return ""
-	}
-
-	workingDir := text[pathStart : pathStart+pathEnd]
-	return workingDir
+	return parseWorkspace(text).primary
 }
 
 // ProcessMessagesWithWorkingDir converts UI messages to cost records with working directory
 func ProcessMessagesWithWorkingDir(messages []UIMessage, fallbackWorkingDir string) []CostRecord {
+	return ProcessMessagesInLocation(messages, fallbackWorkingDir, time.Local)
+}
+
+// ProcessMessagesInLocation converts UI messages to cost records with working
+// directory, writing timestamps in loc
+func ProcessMessagesInLocation(messages []UIMessage, fallbackWorkingDir string, loc *time.Location) []CostRecord {
 	var records []CostRecord
 	var totalCost float64
 
@@ -333,7 +295,7 @@ func ProcessMessagesWithWorkingDir(messages []UIMessage, fallbackWorkingDir stri
 		}
 
 		record := CostRecord{
-			Timestamp:        formatTimestamp(msg.Timestamp),
+			Timestamp:        formatTimestamp(msg.Timestamp, loc),
 			Text:             msg.Text,
 			WorkingDirectory: messageWorkingDir,
 		}
@@ -368,7 +330,7 @@ func ProcessMessagesWithWorkingDir(messages []UIMessage, fallbackWorkingDir stri
 		record.ContextPercentage = extractContextPercentage(msg)
 		record.SearchTermInTranscript = generateSearchTerm(msg, i)
 		record.CostNotes = generateCostNotes(msg)
-		record.TimeApprox = formatTimeApprox(msg.Timestamp)
+		record.TimeApprox = formatTimeApprox(msg.Timestamp, loc)
 
 		records = append(records, record)
 	}
@@ -384,23 +346,7 @@ func ProcessUILogToCSV(inputPath, outputPath string) error {
 	if err != nil {
 		return err
 	}
-
-	// Process messages into cost records
-	records := ProcessMessages(messages)
-
-	// Ensure logs directory exists
-	if err := EnsureLogsDirectory(); err != nil {
-		return err
-	}
-
-	// Write CSV file
-	if err := WriteCSV(outputPath, records); err != nil {
-		return err
-	}
-
-	fmt.Printf("Cost tracker CSV generated: %s\n", outputPath)
-	fmt.Printf("Total records: %d\n", len(records))
-	return nil
+	return writeUILogCSV(messages, outputPath)
 }
 
 // ProcessUILogToCSVAuto automatically generates the output path based on input
@@ -417,10 +363,22 @@ func ProcessUILogToCSVAuto(inputPath string) error {
 
 	// Generate output path
 	taskID := ExtractTaskID(inputPath)
-	outputPath := GenerateOutputPath(taskID, messages[0].Timestamp)
+	return writeUILogCSV(messages, GenerateOutputPath(taskID, messages[0].Timestamp))
+}
+
+// writeUILogCSV writes the cost records of messages to outputPath, masking
+// them when redaction is enabled in the environment
+func writeUILogCSV(messages []UIMessage, outputPath string) error {
+	redactor, err := redactorFor(DefaultOptions())
+	if err != nil {
+		return err
+	}
 
-	// Process the rest
+	// Process messages into cost records
 	records := ProcessMessages(messages)
+	if redactor != nil {
+		records = redactor.RedactRecords(records)
+	}
 
 	// Ensure logs directory exists
 	if err := EnsureLogsDirectory(); err != nil {
@@ -432,112 +390,302 @@ func ProcessUILogToCSVAuto(inputPath string) error {
 		return err
 	}
 
-	fmt.Printf("Cost tracker CSV generated: %s\n", outputPath)
-	fmt.Printf("Total records: %d\n", len(records))
+	log.Printf("Cost tracker CSV generated: %s", outputPath)
+	log.Printf("Total records: %d", len(records))
 	return nil
 }
 
-// findMostRecentWorkingDirectory finds the most recent working directory mentioned in the messages
-func findMostRecentWorkingDirectory(inputPath string) string {
-	// Read the entire file to find the most recent working directory
-	data, err := ioutil.ReadFile(inputPath)
+// taskInput is a task file read in its agent's format and normalized to Cline's
+type taskInput struct {
+	source   Source
+	taskID   string
+	messages []UIMessage
+	// workingDir is the most recent primary working directory of the task
+	workingDir string
+	// fallbackWorkingDir is the first working directory, used for messages
+	// that do not mention one
+	fallbackWorkingDir string
+	// workspaceRoots are every root of the task's workspace, workingDir first
+	workspaceRoots []string
+	// repoConfig holds the overrides of the repository, nil when it has none
+	repoConfig *RepositoryConfig
+	// git is what happened in the repository while the task ran, nil
+	// outside git
+	git *GitActivity
+}
+
+// readTask reads a task file with the source selected by opts and finds its
+// working directories
+func readTask(inputPath string, opts Options) (*taskInput, error) {
+	// Read the task in the agent's format and normalize it to Cline's
+	source, err := sourceFor(opts, inputPath)
 	if err != nil {
-		log.Printf("DEBUG: Error reading file for most recent working directory: %v", err)
-		return ""
+		return nil, err
+	}
+	log.Printf("DEBUG: Reading %s task", source.Name)
+	messages, err := source.ParseUIMessages(inputPath)
+	if err != nil {
+		return nil, err
 	}
 
-	contentStr := string(data)
-	workingDirPattern := "# Current Working Directory ("
-
-	// Find all occurrences of working directory pattern
-	var lastWorkingDir string
-	startIdx := 0
-
-	for {
-		idx := strings.Index(contentStr[startIdx:], workingDirPattern)
-		if idx == -1 {
-			break
-		}
-
-		// Adjust index to absolute position
-		idx += startIdx
-
-		// Find the start of the path
-		pathStart := idx + len(workingDirPattern)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in the file")
+	}
 
-		// Find the end of the path (closing parenthesis)
-		pathEnd := strings.Index(contentStr[pathStart:], ")")
-		if pathEnd == -1 {
-			break
-		}
+	// Find the working directories of the task's workspace
+	mostRecentWorkingDir, fallbackWorkingDir, workspaceRoots := findWorkspace(messages)
+	log.Printf("DEBUG: Using most recent working directory: %s", mostRecentWorkingDir)
+	if len(workspaceRoots) > 1 {
+		log.Printf("DEBUG: Workspace roots: %s", strings.Join(workspaceRoots, ", "))
+	}
 
-		// Extract this working directory
-		workingDir := contentStr[pathStart : pathStart+pathEnd]
+	// Read the repository's overrides once its working directory is known
+	repoConfig, err := LoadRepositoryConfig(mostRecentWorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	if repoConfig != nil && repoConfig.Disabled {
+		return nil, fmt.Errorf("%w in %s", ErrTrackingDisabled, repoConfig.Path)
+	}
 
-		// Only accept valid directory paths (must start with /Users/ and not contain quotes, newlines, or be placeholder text)
-		if strings.HasPrefix(workingDir, "/Users/") && !strings.Contains(workingDir, "\"") && !strings.Contains(workingDir, "\n") && workingDir != "/path/to/directory" {
-			lastWorkingDir = workingDir
-		}
+	// Read the branches and commits of the task's time window
+	gitActivity, err := ReadGitActivity(mostRecentWorkingDir, messages[0].Timestamp, messages[len(messages)-1].Timestamp)
+	if err != nil {
+		log.Printf("Warning: failed to read git history of %s: %v", mostRecentWorkingDir, err)
+	}
+
+	return &taskInput{
+		source:             source,
+		taskID:             ExtractTaskID(inputPath),
+		messages:           messages,
+		workingDir:         mostRecentWorkingDir,
+		fallbackWorkingDir: fallbackWorkingDir,
+		workspaceRoots:     workspaceRoots,
+		repoConfig:         repoConfig,
+		git:                gitActivity,
+	}, nil
+}
 
-		// Move start index past this occurrence
-		startIdx = pathStart + pathEnd + 1
+// ledger builds the ledger of the task
+func (t *taskInput) ledger() *Ledger {
+	ledger := BuildLedger(t.taskID, t.messages, t.workingDir)
+	ledger.Task.Source = t.source.Name
+	ledger.Task.WorkspaceRoots = t.workspaceRoots
+	if t.repoConfig != nil {
+		ledger.Task.CostCentre = t.repoConfig.CostCentre
 	}
+	if t.git != nil {
+		t.git.Apply(ledger)
+	}
+	return ledger
+}
 
-	log.Printf("DEBUG: Found most recent working directory: %s", lastWorkingDir)
-	return lastWorkingDir
+// LoadLedger reads a task file of any source into a ledger without writing
+// anything, masking it when opts enable redaction
+func LoadLedger(inputPath string, opts Options) (*Ledger, error) {
+	task, err := readTask(inputPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = task.repoConfig.Apply(opts, task.workingDir); err != nil {
+		return nil, err
+	}
+	redactor, err := redactorFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	ledger := task.ledger()
+	if redactor != nil {
+		ledger = redactor.RedactLedger(ledger)
+	}
+	return ledger, nil
 }
 
 // ProcessUILogToCSVAutoAt automatically generates the output path based on input
-// and creates the logs directory at the most recent working directory
+// and creates the logs directory at the most recent working directory, with
+// the other options configured in the environment
 func ProcessUILogToCSVAutoAt(inputPath, basePath string) error {
-	log.Printf("DEBUG: ProcessUILogToCSVAutoAt called with inputPath=%s, basePath=%s", inputPath, basePath)
+	opts := DefaultOptions()
+	opts.Format = FormatCSV
+	_, err := ProcessUILogAutoAt(inputPath, opts)
+	return err
+}
 
-	// Parse just enough to get the first message for timestamp
-	messages, err := ParseUIMessages(inputPath)
+// ProcessUILogAutoAt behaves like ProcessUILogToCSVAutoAt, writing records with
+// the writer selected by opts, and returns the task report and ledger so
+// callers can act on them without re-parsing. With WorkspaceRootsEach the
+// output is also written for every other root of a multi-root workspace.
+func ProcessUILogAutoAt(inputPath string, opts Options) (*TaskResult, error) {
+	log.Printf("DEBUG: ProcessUILogAutoAt called with inputPath=%s, format=%s", inputPath, opts.Format)
+
+	if err := validWorkspaceRoots(opts.WorkspaceRoots); err != nil {
+		return nil, err
+	}
+	task, err := readTask(inputPath, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(messages) == 0 {
-		return fmt.Errorf("no messages found in the file")
+	// Apply the overrides of the repository the task worked in
+	primaryOpts, err := task.repoConfig.Apply(opts, task.workingDir)
+	if err != nil {
+		return nil, err
 	}
+	result, err := task.write(primaryOpts, task.workingDir)
+	if err != nil {
+		return nil, err
+	}
+	result.RepoConfig = task.repoConfig
 
-	// Find the most recent working directory from the entire file
-	mostRecentWorkingDir := findMostRecentWorkingDirectory(inputPath)
-	if mostRecentWorkingDir == "" {
-		// Fallback to extracting from beginning of file
-		mostRecentWorkingDir = extractWorkingDirectoryFromFile(inputPath)
+	if opts.WorkspaceRoots != WorkspaceRootsEach {
+		return result, nil
 	}
-	log.Printf("DEBUG: Using most recent working directory: %s", mostRecentWorkingDir)
+	written := map[string]bool{filepath.Dir(result.OutputPath): true}
+	for _, root := range task.workspaceRoots[1:] {
+		// Each root has its own overrides, and may not want the output
+		repoConfig, err := LoadRepositoryConfig(root)
+		if err != nil {
+			return nil, err
+		}
+		if repoConfig != nil && repoConfig.Disabled {
+			log.Printf("Skipping workspace root %s: %v in %s", root, ErrTrackingDisabled, repoConfig.Path)
+			continue
+		}
+		rootOpts, err := repoConfig.Apply(opts, root)
+		if err != nil {
+			return nil, err
+		}
+		logsDir, err := rootOpts.LogsDirectory(root)
+		if err != nil {
+			return nil, err
+		}
+		if written[logsDir] {
+			continue
+		}
+		written[logsDir] = true
+		rootResult, err := task.write(rootOpts, root)
+		if err != nil {
+			return nil, err
+		}
+		result.RootOutputPaths = append(result.RootOutputPaths, rootResult.OutputPath)
+	}
+	return result, nil
+}
 
-	// Use the most recent working directory as the base path for saving CSV
-	actualBasePath := filepath.Join(mostRecentWorkingDir, "ui-log-parser")
+// write writes the records and reports of the task to the logs directory
+// opts select for workingDir
+func (t *taskInput) write(opts Options, workingDir string) (*TaskResult, error) {
+	messages, taskID := t.messages, t.taskID
+	ledger := t.ledger()
 
-	// Generate output path relative to the most recent working directory
-	taskID := ExtractTaskID(inputPath)
-	outputFilename := fmt.Sprintf("task_%s_%s_costs.csv", taskID, formatTimestampForFilename(messages[0].Timestamp))
-	outputPath := filepath.Join(actualBasePath, "logs", outputFilename)
+	writer, err := NewWriter(opts)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := redactorFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save output in the logs directory of the selected output location
+	logsDir, err := opts.LogsDirectory(workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate output path from the file name template
+	outputName, err := opts.OutputName(ledger.Task)
+	if err != nil {
+		return nil, err
+	}
+	outputPath := filepath.Join(logsDir, outputName+"_costs"+writer.Extension())
 
 	log.Printf("DEBUG: Generated outputPath: %s", outputPath)
 
 	// Process the rest with working directory (using fallback for individual messages)
-	fallbackWorkingDir := extractWorkingDirectoryFromFile(inputPath)
-	records := ProcessMessagesWithWorkingDir(messages, fallbackWorkingDir)
+	loc, err := ResolveLocation(opts.TimeZone, messages)
+	if err != nil {
+		return nil, err
+	}
+	records := ProcessMessagesInLocation(messages, t.fallbackWorkingDir, loc)
+	report := BuildTaskReport(taskID, messages, opts.UnchangedReadsOnly)
+	report.Location = loc
+	if t.git != nil {
+		t.git.ApplyRecords(records, messages)
+		report.Git = t.git
+		report.Commits = append([]LedgerCommit(nil), ledger.Commits...)
+		report.BranchCosts = CostByBranch(ledger)
+		report.UncommittedCost = UncommittedCost(ledger)
+	}
 
-	// Ensure logs directory exists at the most recent working directory
-	log.Printf("DEBUG: About to call EnsureLogsDirectoryAt with basePath: %s", actualBasePath)
-	if err := EnsureLogsDirectoryAt(actualBasePath); err != nil {
-		log.Printf("DEBUG: EnsureLogsDirectoryAt failed: %v", err)
-		return err
+	// Convert costs to the display currency at each request's date
+	converter, err := NewCurrencyConverter(opts.Currency, opts.FXRatesPath, loc)
+	if err != nil {
+		return nil, err
+	}
+	if converter != nil {
+		// Requests dated before the first rate keep only their USD cost
+		converter.ConvertRecords(records, messages)
+		var skipped int
+		report.ConvertedTotalCost, skipped = converter.ConvertLedger(ledger)
+		if skipped > 0 {
+			log.Printf("Warning: %d API requests of task %s have no %s rate on or before their date, leaving them unconverted", skipped, taskID, converter.Currency)
+		}
+		report.Currency = converter.Currency
 	}
-	log.Printf("DEBUG: EnsureLogsDirectoryAt succeeded")
 
-	// Write CSV file
-	if err := WriteCSV(outputPath, records); err != nil {
-		return err
+	// Mask secrets and personal data before anything reaches the logs directory
+	if redactor != nil {
+		records = redactor.RedactRecords(records)
+		ledger = redactor.RedactLedger(ledger)
+		redactor.RedactReport(report)
 	}
 
-	fmt.Printf("Cost tracker CSV generated: %s\n", outputPath)
-	fmt.Printf("Total records: %d\n", len(records))
-	return nil
+	// Ensure logs directory exists, with any subdirectories of the file name
+	log.Printf("DEBUG: Creating logs directory: %s", logsDir)
+	if err := opts.CreateLogsDirectory(logsDir); err != nil {
+		log.Printf("DEBUG: Creating logs directory failed: %v", err)
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		log.Printf("DEBUG: Creating logs directory failed: %v", err)
+		return nil, err
+	}
+
+	// Write cost records in the selected format
+	if ledgerWriter, ok := writer.(LedgerWriter); ok {
+		err = ledgerWriter.WriteLedger(outputPath, ledger)
+	} else {
+		err = writer.Write(outputPath, records)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Cost tracker %s generated: %s", strings.ToUpper(strings.Trim(writer.Extension(), "._")), outputPath)
+	result := &TaskResult{
+		OutputPath: outputPath,
+		Report:     report,
+		Ledger:     ledger,
+	}
+
+	// Write the cost reports next to the records unless they are turned off
+	if !opts.SkipReports {
+		result.ReportPath = filepath.Join(logsDir, outputName+"_report.txt")
+		if err := WriteTextReport(result.ReportPath, report); err != nil {
+			return nil, err
+		}
+		result.MarkdownPath = strings.TrimSuffix(result.ReportPath, ".txt") + ".md"
+		if err := WriteMarkdownReport(result.MarkdownPath, report); err != nil {
+			return nil, err
+		}
+		result.HTMLPath = strings.TrimSuffix(result.ReportPath, ".txt") + ".html"
+		if err := WriteHTMLReport(result.HTMLPath, fmt.Sprintf("Task %s cost report", taskID), []*Ledger{ledger}, HTMLReportOptions{Location: loc, Converter: converter}); err != nil {
+			return nil, err
+		}
+		log.Printf("Cost report generated: %s, %s and %s", result.ReportPath, result.MarkdownPath, result.HTMLPath)
+	}
+
+	log.Printf("Total records: %d", len(records))
+	return result, nil
 }
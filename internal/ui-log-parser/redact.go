@@ -0,0 +1,205 @@
+package uilogparser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that configure redaction
+const (
+	RedactEnvVar         = "COST_TRACKER_REDACT"
+	RedactPatternsEnvVar = "COST_TRACKER_REDACT_PATTERNS"
+)
+
+// Built-in redaction rule names, recorded in the Redactions column
+const (
+	RedactAPIKey   = "api_key"
+	RedactToken    = "token"
+	RedactSecret   = "secret"
+	RedactEmail    = "email"
+	RedactHomeUser = "home_user"
+	RedactCustom   = "custom"
+)
+
+// RedactionRule masks every match of Pattern with Replacement. Replacement may
+// refer to capture groups, e.g. ${1}.
+type RedactionRule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// defaultRedactionRules mask credentials, email addresses and the username in
+// home directory paths. Order matters: specific key formats run before the
+// generic key=value rule so they are counted under their own name.
+var defaultRedactionRules = []RedactionRule{
+	{RedactAPIKey, regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`), "[REDACTED:api_key]"},
+	{RedactAPIKey, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), "[REDACTED:api_key]"},
+	{RedactAPIKey, regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`), "[REDACTED:api_key]"},
+	{RedactToken, regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`), "[REDACTED:token]"},
+	{RedactToken, regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "[REDACTED:token]"},
+	{RedactToken, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), "[REDACTED:token]"},
+	{RedactToken, regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]{16,}=*`), "${1}[REDACTED:token]"},
+	{RedactSecret, regexp.MustCompile(`(?i)\b((?:api[_-]?key|access[_-]?key|secret|token|password|passwd)\\?["']?\s*[:=]\s*\\?["']?)([^\s"'\\]{8,})`), "${1}[REDACTED:secret]"},
+	{RedactEmail, regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`), "[REDACTED:email]"},
+	// Home directories start a path: at the start of the text, after a
+	// character that cannot be part of one, or after an escape such as the \n
+	// of JSON encoded request text. Windows paths go first, as working
+	// directories record them with slashes.
+	{RedactHomeUser, regexp.MustCompile(`(?i)(^|[^A-Za-z0-9_.\\/-]|\\[nrt])[A-Z]:(?:\\\\?|/)Users(?:\\\\?|/)[^\\/\s"')]+`), "${1}~"},
+	{RedactHomeUser, regexp.MustCompile(`(^|[^A-Za-z0-9_.~\\/-]|\\[nrt])(?:/Users|/home)/[^/\s"'\\)]+`), "${1}~"},
+}
+
+// Redactor masks secrets and personal data in text before it is written
+type Redactor struct {
+	rules []RedactionRule
+}
+
+// NewRedactor returns a redactor applying the built-in rules followed by the
+// given user-defined regular expressions
+func NewRedactor(patterns []string) (*Redactor, error) {
+	rules := append([]RedactionRule{}, defaultRedactionRules...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+		rules = append(rules, RedactionRule{RedactCustom, re, "[REDACTED:custom]"})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// redactorFor returns the redactor configured by opts, nil when redaction is off
+func redactorFor(opts Options) (*Redactor, error) {
+	if !opts.Redact {
+		return nil, nil
+	}
+	return NewRedactor(opts.RedactPatterns)
+}
+
+// RedactString masks text, adding the number of matches of each rule to counts
+func (r *Redactor) RedactString(text string, counts map[string]int) string {
+	for _, rule := range r.rules {
+		matches := rule.Pattern.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		counts[rule.Name] += len(matches)
+		text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
+
+// RedactRecords masks the free text fields of each record and lists what was
+// masked in its Redactions column
+func (r *Redactor) RedactRecords(records []CostRecord) []CostRecord {
+	redacted := make([]CostRecord, len(records))
+	for i, record := range records {
+		// Summary and search term columns repeat the text, so a secret is
+		// counted once per rule rather than once per column
+		counts := make(map[string]int)
+		for _, field := range []*string{
+			&record.RequestSummary, &record.Text, &record.ClineAction,
+			&record.SearchTermInTranscript, &record.CostNotes, &record.WorkingDirectory,
+		} {
+			fieldCounts := make(map[string]int)
+			*field = r.RedactString(*field, fieldCounts)
+			for name, count := range fieldCounts {
+				if count > counts[name] {
+					counts[name] = count
+				}
+			}
+		}
+		record.Redactions = formatRedactionCounts(counts)
+		redacted[i] = record
+	}
+	return redacted
+}
+
+// RedactLedger returns a copy of the ledger with its prompt, paths, message
+// text and commit subjects masked, recording what was masked on its task
+func (r *Redactor) RedactLedger(ledger *Ledger) *Ledger {
+	counts := make(map[string]int)
+	redacted := &Ledger{
+		Task:        ledger.Task,
+		APIRequests: ledger.APIRequests,
+		ToolCalls:   make([]LedgerToolCall, len(ledger.ToolCalls)),
+		Messages:    make([]LedgerMessage, len(ledger.Messages)),
+		Commits:     make([]LedgerCommit, len(ledger.Commits)),
+	}
+	redacted.Task.Prompt = r.RedactString(ledger.Task.Prompt, counts)
+	redacted.Task.WorkingDirectory = r.RedactString(ledger.Task.WorkingDirectory, counts)
+	redacted.Task.RepositoryRoot = r.RedactString(ledger.Task.RepositoryRoot, counts)
+	redacted.Task.Subdirectory = r.RedactString(ledger.Task.Subdirectory, counts)
+	redacted.Task.WorkspaceRoots = make([]string, len(ledger.Task.WorkspaceRoots))
+	for i, root := range ledger.Task.WorkspaceRoots {
+		redacted.Task.WorkspaceRoots[i] = r.RedactString(root, counts)
+	}
+	for i, call := range ledger.ToolCalls {
+		call.Path = r.RedactString(call.Path, counts)
+		redacted.ToolCalls[i] = call
+	}
+	for i, msg := range ledger.Messages {
+		msg.Text = r.RedactString(msg.Text, counts)
+		redacted.Messages[i] = msg
+	}
+	for i, commit := range ledger.Commits {
+		commit.Subject = r.RedactString(commit.Subject, counts)
+		redacted.Commits[i] = commit
+	}
+	redacted.Task.Redacted = true
+	redacted.Task.Redactions = formatRedactionCounts(counts)
+	return redacted
+}
+
+// RedactReport masks the prompt, request summaries, paths and commit subjects
+// of a task report in place
+func (r *Redactor) RedactReport(report *TaskReport) {
+	counts := make(map[string]int)
+	report.Prompt = r.RedactString(report.Prompt, counts)
+	for i := range report.TopRequests {
+		report.TopRequests[i].Summary = r.RedactString(report.TopRequests[i].Summary, counts)
+	}
+	for i := range report.FileCosts {
+		report.FileCosts[i].Path = r.RedactString(report.FileCosts[i].Path, counts)
+	}
+	for i := range report.RedundantReads {
+		report.RedundantReads[i].Path = r.RedactString(report.RedundantReads[i].Path, counts)
+	}
+	for i := range report.LoopSignals {
+		report.LoopSignals[i].Detail = r.RedactString(report.LoopSignals[i].Detail, counts)
+	}
+	for i := range report.Commits {
+		report.Commits[i].Subject = r.RedactString(report.Commits[i].Subject, counts)
+	}
+}
+
+// formatRedactionCounts renders counts as "email:2;token:1", sorted by rule name
+func formatRedactionCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ":" + strconv.Itoa(counts[name])
+	}
+	return strings.Join(parts, ";")
+}
+
+// redactPatternsFromEnv reads user-defined redaction patterns, one per line
+func redactPatternsFromEnv() []string {
+	var patterns []string
+	for _, line := range strings.Split(os.Getenv(RedactPatternsEnvVar), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
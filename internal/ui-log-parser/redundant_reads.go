@@ -0,0 +1,112 @@
+package uilogparser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UnchangedReadsEnvVar is the environment variable that limits redundant
+// reads to re-reads of unchanged files
+const UnchangedReadsEnvVar = "COST_TRACKER_UNCHANGED_READS_ONLY"
+
+// charsPerToken is the rough number of characters per token used to estimate
+// token counts from text length
+const charsPerToken = 4
+
+// DetectRedundantReads finds read_file calls on paths that were already read
+// earlier in the task. A re-read is unchanged when no write_to_file or
+// replace_in_file touched the path since the previous read; when unchangedOnly
+// is set only those re-reads are reported. The wasted tokens are estimated
+// from the size of the file content returned to the model in the next request.
+func DetectRedundantReads(messages []UIMessage, unchangedOnly bool) []RedundantRead {
+	reads := make(map[string]*RedundantRead)
+	writeCount := make(map[string]int)
+	writesAtLastRead := make(map[string]int)
+	// pending are the re-reads whose results arrive with the next request,
+	// by the path as the model wrote it, which Cline repeats in the result
+	var pending []pendingRead
+
+	for _, msg := range messages {
+		if req, ok := ParseAPIRequest(msg); ok {
+			// The results of the previous turn's tool calls are sent in this request
+			for _, p := range pending {
+				size := toolResultSize(req.Request, "read_file", p.rawPath)
+				reads[p.path].EstimatedTokens += int64(size / charsPerToken)
+			}
+			pending = nil
+			continue
+		}
+
+		call, ok := ParseToolCall(msg)
+		if !ok || call.Path == "" {
+			continue
+		}
+		path := filepath.Clean(call.Path)
+
+		switch call.Tool {
+		case "write_to_file", "replace_in_file":
+			writeCount[path]++
+		case "read_file":
+			read, exists := reads[path]
+			if !exists {
+				read = &RedundantRead{Path: path}
+				reads[path] = read
+			}
+			read.Reads++
+
+			if read.Reads > 1 {
+				unchanged := writeCount[path] == writesAtLastRead[path]
+				if unchanged {
+					read.UnchangedReads++
+				}
+				if unchanged || !unchangedOnly {
+					read.RedundantReads++
+					pending = append(pending, pendingRead{path: path, rawPath: call.Path})
+				}
+			}
+			writesAtLastRead[path] = writeCount[path]
+		}
+	}
+
+	var result []RedundantRead
+	for _, read := range reads {
+		if read.RedundantReads > 0 {
+			result = append(result, *read)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].EstimatedTokens != result[j].EstimatedTokens {
+			return result[i].EstimatedTokens > result[j].EstimatedTokens
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// pendingRead is a re-read waiting for its result: path is the cleaned path
+// that keys the read counts and rawPath the path as the tool call gave it
+type pendingRead struct {
+	path    string
+	rawPath string
+}
+
+// toolResultSize returns the length of the result Cline sent back to the model
+// for a tool call, e.g. "[read_file for 'main.go'] Result:" followed by the file
+func toolResultSize(request, tool, path string) int {
+	marker := fmt.Sprintf("[%s for '%s'] Result:", tool, path)
+	start := strings.Index(request, marker)
+	if start == -1 {
+		return 0
+	}
+	result := request[start+len(marker):]
+
+	end := len(result)
+	for _, terminator := range []string{"\n\n[", "<environment_details>"} {
+		if idx := strings.Index(result, terminator); idx != -1 && idx < end {
+			end = idx
+		}
+	}
+	return len(strings.TrimSpace(result[:end]))
+}
@@ -0,0 +1,101 @@
+package uilogparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// toolMessage returns a tool message calling tool on path
+func toolMessage(tool, path string) UIMessage {
+	text, _ := json.Marshal(ToolCall{Tool: tool, Path: path})
+	return UIMessage{Type: "say", Say: "tool", Text: string(text)}
+}
+
+// requestMessage returns an api_req_started message sending request
+func requestMessage(request string, cost float64) UIMessage {
+	text, _ := json.Marshal(APIRequest{Request: request, Cost: cost})
+	return UIMessage{Type: "say", Say: "api_req_started", Text: string(text)}
+}
+
+func TestDetectRedundantReads(t *testing.T) {
+	content := strings.Repeat("x", 400)
+	tests := []struct {
+		name       string
+		path       string
+		wantPath   string
+		wantTokens int64
+	}{
+		{"plain path", "main.go", "main.go", 100},
+		{"dot slash", "./main.go", "main.go", 100},
+		{"trailing slash", "docs/", "docs", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := "[read_file for '" + tt.path + "'] Result:\n" + content + "\n\n<environment_details>"
+			messages := []UIMessage{
+				toolMessage("readFile", tt.path),
+				requestMessage(result, 0.01),
+				toolMessage("readFile", tt.path),
+				requestMessage(result, 0.01),
+			}
+			reads := DetectRedundantReads(messages, false)
+			if len(reads) != 1 {
+				t.Fatalf("got %d redundant reads, want 1", len(reads))
+			}
+			if reads[0].Path != tt.wantPath || reads[0].RedundantReads != 1 || reads[0].EstimatedTokens != tt.wantTokens {
+				t.Errorf("got %+v, want path %s, 1 redundant read and %d tokens", reads[0], tt.wantPath, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestDetectRedundantReadsModes(t *testing.T) {
+	result := func(path string) string {
+		return "[read_file for '" + path + "'] Result:\n" + strings.Repeat("x", 400) + "\n\n<environment_details>"
+	}
+	messages := []UIMessage{
+		toolMessage("readFile", "main.go"),
+		toolMessage("readFile", "edited.go"),
+		requestMessage(result("main.go")+result("edited.go"), 0.01),
+		toolMessage("editedExistingFile", "main.go"),
+		toolMessage("editedExistingFile", "edited.go"),
+		toolMessage("readFile", "main.go"),
+		toolMessage("readFile", "edited.go"),
+		requestMessage(result("main.go")+result("edited.go"), 0.01),
+		toolMessage("readFile", "main.go"),
+		requestMessage(result("main.go"), 0.01),
+	}
+	tests := []struct {
+		unchangedOnly bool
+		want          []RedundantRead
+	}{
+		{false, []RedundantRead{
+			{Path: "main.go", Reads: 3, RedundantReads: 2, UnchangedReads: 1, EstimatedTokens: 200},
+			{Path: "edited.go", Reads: 2, RedundantReads: 1, UnchangedReads: 0, EstimatedTokens: 100},
+		}},
+		{true, []RedundantRead{
+			{Path: "main.go", Reads: 3, RedundantReads: 1, UnchangedReads: 1, EstimatedTokens: 100},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("unchanged only %v", tt.unchangedOnly), func(t *testing.T) {
+			if got := DetectRedundantReads(messages, tt.unchangedOnly); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			report := BuildTaskReport("1", messages, tt.unchangedOnly)
+			if !reflect.DeepEqual(report.RedundantReads, tt.want) {
+				t.Errorf("report lists %+v, want %+v", report.RedundantReads, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnchangedReadsOnlyFromEnv(t *testing.T) {
+	t.Setenv(UnchangedReadsEnvVar, "true")
+	if opts := DefaultOptions(); !opts.UnchangedReadsOnly {
+		t.Errorf("%s=true left UnchangedReadsOnly off", UnchangedReadsEnvVar)
+	}
+}
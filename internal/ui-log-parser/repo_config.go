@@ -0,0 +1,135 @@
+package uilogparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepositoryConfigFile is the name of the file a repository keeps next to its
+// .git to override tracking settings for tasks working in it
+const RepositoryConfigFile = ".cline-cost-tracker.yaml"
+
+// ErrTrackingDisabled is returned when a repository config disables tracking
+var ErrTrackingDisabled = errors.New("tracking disabled by repository config")
+
+// RepositoryConfig holds the overrides of one repository
+type RepositoryConfig struct {
+	// Path is the file the overrides were read from
+	Path string `yaml:"-"`
+	// Root is the repository root holding the file
+	Root string `yaml:"-"`
+	// Disabled turns tracking off for the repository
+	Disabled bool `yaml:"disabled"`
+	// Confidential keeps all output outside the repository, for repositories
+	// that must never have transcript text written into them
+	Confidential bool `yaml:"confidential"`
+	Output       struct {
+		// Directory is resolved against the repository root unless absolute
+		Directory string `yaml:"directory"`
+		FileName  string `yaml:"file_name"`
+	} `yaml:"output"`
+	// Budget limits the repository's spend over the budget period, in USD
+	Budget    float64 `yaml:"budget"`
+	Redaction struct {
+		// Enabled turns redaction on, it cannot be turned off here
+		Enabled bool `yaml:"enabled"`
+		// Patterns are masked in addition to the configured ones
+		Patterns []string `yaml:"patterns"`
+	} `yaml:"redaction"`
+	// CostCentre tags every task of the repository
+	CostCentre string `yaml:"cost_centre"`
+}
+
+// LoadRepositoryConfig reads the RepositoryConfigFile at the root of the
+// repository holding workingDir, see ResolveRepository. It returns nil when
+// there is none. Unknown keys are rejected, and callers should not write
+// anything for a repository whose file cannot be read, since it may be
+// confidential.
+func LoadRepositoryConfig(workingDir string) (*RepositoryConfig, error) {
+	if workingDir == "" {
+		return nil, nil
+	}
+	root := ResolveRepository(workingDir).Root
+	path := filepath.Join(root, RepositoryConfigFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %v", err)
+	}
+
+	rc := &RepositoryConfig{Path: path, Root: root}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid repository config %s: %v", path, err)
+	}
+	return rc, nil
+}
+
+// Apply returns opts with the repository's overrides. Confidential
+// repositories have output that would land inside them moved to the data
+// location.
+func (rc *RepositoryConfig) Apply(opts Options, workingDir string) (Options, error) {
+	if rc == nil {
+		return opts, nil
+	}
+	if rc.Output.Directory != "" {
+		dir := expandHome(rc.Output.Directory)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(rc.Root, dir)
+		}
+		opts.OutputLocation = OutputLocationRepo
+		opts.OutputDir = dir
+	}
+	if rc.Output.FileName != "" {
+		opts.FileName = rc.Output.FileName
+	}
+	if rc.Redaction.Enabled {
+		opts.Redact = true
+	}
+	if len(rc.Redaction.Patterns) > 0 {
+		opts.Redact = true
+		opts.RedactPatterns = append(append([]string(nil), opts.RedactPatterns...), rc.Redaction.Patterns...)
+	}
+
+	if rc.Confidential {
+		logsDir, err := opts.LogsDirectory(workingDir)
+		if err != nil {
+			return opts, err
+		}
+		if isWithin(logsDir, rc.Root) {
+			log.Printf("Repository %s is confidential, writing to the %s output location", rc.Root, OutputLocationData)
+			opts.OutputLocation = OutputLocationData
+			opts.OutputDir = ""
+		}
+	}
+	return opts, nil
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
@@ -0,0 +1,314 @@
+package uilogparser
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// topRequestCount is the number of most expensive requests listed in reports
+const topRequestCount = 5
+
+// TaskReport summarises the cost of a single task
+type TaskReport struct {
+	TaskID           string
+	Prompt           string
+	StartedAt        int64
+	EndedAt          int64
+	TotalCost        float64
+	APIRequests      int
+	TokensIn         int64
+	TokensOut        int64
+	CacheWrites      int64
+	CacheReads       int64
+	TopRequests      []ExpensiveRequest
+	ToolUsage        []ToolUsage
+	FileCosts        []FileCost
+	ExtensionCosts   []CostRollup
+	LanguageCosts    []CostRollup
+	UnattributedCost float64
+	RedundantReads   []RedundantRead
+	LoopSignals      []LoopSignal
+	ContextEvents    []ContextEvent
+	PeakContext      int64
+	Completion       CompletionStats
+	// Git is what happened in the task's repository, nil outside git.
+	// Commits, BranchCosts and UncommittedCost split the cost by the commit
+	// that shipped each request and the branch it was made on.
+	Git             *GitActivity
+	Commits         []LedgerCommit
+	BranchCosts     []BranchCost
+	UncommittedCost float64
+	// Location is the zone report timestamps are written in, local when nil
+	Location *time.Location
+	// Currency is the display currency, empty when costs are only shown in USD
+	Currency string
+	// ConvertedTotalCost is TotalCost in Currency, converted request by request
+	ConvertedTotalCost float64
+}
+
+// OngoingLoops returns the loop signals still going on at the task's last
+// message
+func (r *TaskReport) OngoingLoops() []LoopSignal {
+	var ongoing []LoopSignal
+	for _, signal := range r.LoopSignals {
+		if signal.Ongoing {
+			ongoing = append(ongoing, signal)
+		}
+	}
+	return ongoing
+}
+
+// BuildTaskReport analyses the messages of a task and builds its report. When
+// unchangedReadsOnly is set, only re-reads of unchanged files are listed as
+// redundant.
+func BuildTaskReport(taskID string, messages []UIMessage, unchangedReadsOnly bool) *TaskReport {
+	ledger := BuildLedger(taskID, messages, "")
+	report := &TaskReport{
+		TaskID:      taskID,
+		Prompt:      ledger.Task.Prompt,
+		StartedAt:   ledger.Task.StartedAt,
+		EndedAt:     ledger.Task.EndedAt,
+		TotalCost:   ledger.Task.TotalCost,
+		APIRequests: ledger.Task.APIRequests,
+		TokensIn:    ledger.Task.TokensIn,
+		TokensOut:   ledger.Task.TokensOut,
+		CacheWrites: ledger.Task.CacheWrites,
+		CacheReads:  ledger.Task.CacheReads,
+		TopRequests: topRequests(messages, topRequestCount),
+		ToolUsage:   toolUsage(ledger),
+	}
+
+	report.FileCosts, report.UnattributedCost = AttributeFileCosts(messages)
+	report.ExtensionCosts = RollupByExtension(report.FileCosts)
+	report.LanguageCosts = RollupByLanguage(report.FileCosts)
+	report.RedundantReads = DetectRedundantReads(messages, unchangedReadsOnly)
+	report.LoopSignals = DetectLoops(messages, DefaultLoopThresholds)
+	report.ContextEvents = DetectContextEvents(messages)
+	report.PeakContext = PeakContextTokens(messages)
+	report.Completion = AnalyzeCompletion(messages)
+
+	return report
+}
+
+// formatTime formats a millisecond timestamp in the report's zone
+func (r *TaskReport) formatTime(ts int64) string {
+	if r.Location == nil {
+		return formatTimestamp(ts, time.Local)
+	}
+	return formatTimestamp(ts, r.Location)
+}
+
+// CacheHitRatio returns the share of input tokens that were read from the prompt cache
+func (r *TaskReport) CacheHitRatio() float64 {
+	input := r.TokensIn + r.CacheWrites + r.CacheReads
+	if input == 0 {
+		return 0
+	}
+	return float64(r.CacheReads) / float64(input)
+}
+
+// Duration returns the time between the first and last message of the task
+func (r *TaskReport) Duration() time.Duration {
+	return time.Duration(r.EndedAt-r.StartedAt) * time.Millisecond
+}
+
+// topRequests returns the most expensive API requests, most expensive first
+func topRequests(messages []UIMessage, count int) []ExpensiveRequest {
+	var requests []ExpensiveRequest
+	for _, msg := range messages {
+		req, ok := ParseAPIRequest(msg)
+		if !ok || req.Cost == 0 {
+			continue
+		}
+		requests = append(requests, ExpensiveRequest{
+			Timestamp:   msg.Timestamp,
+			Cost:        req.Cost,
+			TokensIn:    req.TokensIn,
+			TokensOut:   req.TokensOut,
+			CacheWrites: req.CacheWrites,
+			CacheReads:  req.CacheReads,
+			Summary:     summarizeRequest(req.Request),
+		})
+	}
+
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Cost > requests[j].Cost
+	})
+	if len(requests) > count {
+		requests = requests[:count]
+	}
+	return requests
+}
+
+// summarizeRequest returns the first meaningful line of a request, which is
+// the task, the user's feedback or the first tool result sent to the model
+func summarizeRequest(request string) string {
+	for _, line := range strings.Split(request, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "<task>" || line == "<feedback>" {
+			continue
+		}
+		return truncateDetail(line)
+	}
+	return ""
+}
+
+// toolUsage counts calls per tool and splits the cost of each API request
+// evenly across the tool calls it issued
+func toolUsage(ledger *Ledger) []ToolUsage {
+	callsPerRequest := make(map[string]int)
+	for _, call := range ledger.ToolCalls {
+		callsPerRequest[call.RequestID]++
+	}
+	requestCost := make(map[string]float64)
+	for _, req := range ledger.APIRequests {
+		requestCost[req.ID] = req.Cost
+	}
+
+	usage := make(map[string]*ToolUsage)
+	for _, call := range ledger.ToolCalls {
+		u, exists := usage[call.Tool]
+		if !exists {
+			u = &ToolUsage{Tool: call.Tool}
+			usage[call.Tool] = u
+		}
+		u.Calls++
+		if call.RequestID != "" {
+			u.Cost += requestCost[call.RequestID] / float64(callsPerRequest[call.RequestID])
+		}
+	}
+
+	result := make([]ToolUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Calls != result[j].Calls {
+			return result[i].Calls > result[j].Calls
+		}
+		return result[i].Tool < result[j].Tool
+	})
+	return result
+}
+
+// FormatTextReport renders a task report as plain text
+func FormatTextReport(report *TaskReport) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Task %s\n", report.TaskID)
+	fmt.Fprintf(&b, "Total cost: $%.6f across %d API requests\n", report.TotalCost, report.APIRequests)
+	if report.Currency != "" {
+		fmt.Fprintf(&b, "Total cost in %s: %.6f\n", report.Currency, report.ConvertedTotalCost)
+	}
+
+	fmt.Fprintf(&b, "\nCompletion\n")
+	if report.Completion.Completions == 0 {
+		fmt.Fprintf(&b, "  Not completed yet\n")
+	} else {
+		fmt.Fprintf(&b, "  First completed at %s, %d completions, reopened %d times\n",
+			report.formatTime(report.Completion.FirstCompletion), report.Completion.Completions, report.Completion.Reopens)
+		fmt.Fprintf(&b, "  Cost to first completion: $%.6f\n", report.Completion.CostToFirstCompletion)
+		fmt.Fprintf(&b, "  Rework after completion:  $%.6f (%.0f%%)\n", report.Completion.ReworkCost, report.Completion.ReworkRatio()*100)
+	}
+
+	if report.Git != nil {
+		fmt.Fprintf(&b, "\nBranches and commits\n")
+		fmt.Fprintf(&b, "  Started on %s, ended on %s\n", formatGitHead(report.Git.Start), formatGitHead(report.Git.End))
+		for _, c := range report.BranchCosts {
+			fmt.Fprintf(&b, "  $%10.6f  %3d requests  %s\n", c.Cost, c.Requests, c.Branch)
+		}
+		fmt.Fprintf(&b, "\nCost by commit\n")
+		if len(report.Commits) == 0 {
+			fmt.Fprintf(&b, "  (no commits during the task)\n")
+		}
+		for _, c := range report.Commits {
+			fmt.Fprintf(&b, "  $%10.6f  %3d requests  %s %s  %s\n", c.Cost, c.Requests, ShortHash(c.Hash), formatBranch(c.Branch), c.Subject)
+		}
+		fmt.Fprintf(&b, "  $%10.6f  uncommitted (requests after the last commit)\n", report.UncommittedCost)
+	}
+
+	fmt.Fprintf(&b, "\nCost by file\n")
+	if len(report.FileCosts) == 0 {
+		fmt.Fprintf(&b, "  (no files touched)\n")
+	}
+	for _, f := range report.FileCosts {
+		fmt.Fprintf(&b, "  $%10.6f  %3d requests  %3d tool calls  %s (%s)\n", f.Cost, f.Requests, f.ToolCalls, f.Path, f.Language)
+	}
+	fmt.Fprintf(&b, "  $%10.6f  unattributed (requests that touched no files)\n", report.UnattributedCost)
+
+	fmt.Fprintf(&b, "\nCost by language\n")
+	for _, r := range report.LanguageCosts {
+		fmt.Fprintf(&b, "  $%10.6f  %3d files  %s\n", r.Cost, r.Files, r.Key)
+	}
+
+	fmt.Fprintf(&b, "\nCost by extension\n")
+	for _, r := range report.ExtensionCosts {
+		fmt.Fprintf(&b, "  $%10.6f  %3d files  %s\n", r.Cost, r.Files, r.Key)
+	}
+
+	fmt.Fprintf(&b, "\nRedundant reads\n")
+	if len(report.RedundantReads) == 0 {
+		fmt.Fprintf(&b, "  (none)\n")
+	}
+	var wastedTokens int64
+	for _, r := range report.RedundantReads {
+		fmt.Fprintf(&b, "  %7d tokens  %3d reads  %3d redundant  %3d unchanged  %s\n", r.EstimatedTokens, r.Reads, r.RedundantReads, r.UnchangedReads, r.Path)
+		wastedTokens += r.EstimatedTokens
+	}
+	if wastedTokens > 0 {
+		fmt.Fprintf(&b, "  ~%d tokens wasted on redundant reads\n", wastedTokens)
+	}
+
+	fmt.Fprintf(&b, "\nLoop detection\n")
+	if len(report.LoopSignals) == 0 {
+		fmt.Fprintf(&b, "  (no loops detected)\n")
+	}
+	for _, signal := range report.LoopSignals {
+		fmt.Fprintf(&b, "  %s\n", FormatLoopSignal(signal))
+	}
+
+	fmt.Fprintf(&b, "\nContext window\n")
+	fmt.Fprintf(&b, "  Peak context: %d tokens\n", report.PeakContext)
+	counts := make(map[string]int)
+	var condenseCost float64
+	for _, event := range report.ContextEvents {
+		counts[event.Kind]++
+		if event.Kind == ContextCondensed {
+			condenseCost += event.Cost
+		}
+	}
+	fmt.Fprintf(&b, "  Hit the ceiling %d times: %d condensed, %d unexplained drops\n",
+		len(report.ContextEvents), counts[ContextCondensed], counts[ContextDropped])
+	if counts[ContextCondensed] > 0 {
+		fmt.Fprintf(&b, "  Condense calls cost: $%.6f\n", condenseCost)
+	}
+	for _, event := range report.ContextEvents {
+		fmt.Fprintf(&b, "  %s  %-12s %7d -> %7d tokens\n", report.formatTime(event.Timestamp), event.Kind, event.TokensBefore, event.TokensAfter)
+	}
+
+	return b.String()
+}
+
+// formatGitHead describes a checked out branch and commit
+func formatGitHead(head GitHead) string {
+	if head.Commit == "" {
+		return formatBranch(head.Branch) + " (no commits)"
+	}
+	return formatBranch(head.Branch) + " at " + ShortHash(head.Commit)
+}
+
+// formatBranch names a branch, or a detached HEAD when empty
+func formatBranch(branch string) string {
+	if branch == "" {
+		return detachedBranch
+	}
+	return branch
+}
+
+// WriteTextReport writes a task report as plain text to a file
+func WriteTextReport(filename string, report *TaskReport) error {
+	return os.WriteFile(filename, []byte(FormatTextReport(report)), 0644)
+}
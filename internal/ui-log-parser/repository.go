@@ -0,0 +1,91 @@
+package uilogparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repositoryMarkers mark the root of a project outside git
+var repositoryMarkers = []string{"go.mod", "package.json"}
+
+// Repository is the repository a task worked in
+type Repository struct {
+	// Name files the task's costs: the base name of the checkout, or of the
+	// main checkout for a git worktree
+	Name string
+	// Root is the top of the checkout holding the working directory
+	Root string
+	// Subdirectory is the working directory relative to Root, with slashes,
+	// empty at the root
+	Subdirectory string
+}
+
+// ResolveRepository returns the repository holding a working directory: the
+// nearest directory at or above it with .git, which is a file in worktrees and
+// submodules, else the nearest with a repository marker, else the working
+// directory itself. A submodule is a repository of its own.
+func ResolveRepository(workingDir string) Repository {
+	if workingDir == "" {
+		return Repository{}
+	}
+	dir := filepath.Clean(workingDir)
+
+	root, ok := gitRoot(dir)
+	name := ""
+	if ok {
+		name = gitRepositoryName(root)
+	} else {
+		root = markedRoot(dir)
+	}
+	if name == "" {
+		name = filepath.Base(root)
+	}
+
+	repo := Repository{Name: name, Root: root}
+	if rel, err := filepath.Rel(root, dir); err == nil && rel != "." {
+		repo.Subdirectory = filepath.ToSlash(rel)
+	}
+	return repo
+}
+
+// markedRoot returns the nearest directory at or above dir holding a
+// repository marker, or dir when there is none or it is not an absolute path
+func markedRoot(dir string) string {
+	if !filepath.IsAbs(dir) {
+		return dir
+	}
+	for current := dir; ; {
+		for _, marker := range repositoryMarkers {
+			if _, err := os.Stat(filepath.Join(current, marker)); err == nil {
+				return current
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// gitRepositoryName names the repository checked out at root. A worktree is
+// named after the repository it was added to, found through the common
+// directory its git directory points at; a submodule, whose git directory has
+// no common directory, after its own checkout.
+func gitRepositoryName(root string) string {
+	dir, err := gitDir(root)
+	if err != nil || dir == filepath.Join(root, ".git") {
+		return ""
+	}
+	common := gitCommonDir(dir)
+	switch {
+	case common == dir:
+		return ""
+	case filepath.Base(common) == ".git":
+		return filepath.Base(filepath.Dir(common))
+	}
+	// The common directory of a bare repository, or of a submodule under
+	// .git/modules, is named after the repository
+	return strings.TrimSuffix(filepath.Base(common), ".git")
+}
@@ -0,0 +1,50 @@
+package uilogparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mkfile creates a file under root, with its parent directories
+func mkfile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveRepository(t *testing.T) {
+	root := t.TempDir()
+	// api is a git checkout with a worktree, web a project outside git
+	mkfile(t, root, "api/.git/HEAD", "ref: refs/heads/main\n")
+	mkfile(t, root, "api/.git/worktrees/api-fix/commondir", "../..\n")
+	mkfile(t, root, "api-fix/.git", "gitdir: "+filepath.Join(root, "api/.git/worktrees/api-fix")+"\n")
+	mkfile(t, root, "web/package.json", "{}")
+	mkfile(t, root, "scratch/notes.txt", "")
+
+	tests := []struct {
+		name       string
+		workingDir string
+		want       Repository
+	}{
+		{"git root", filepath.Join(root, "api"), Repository{Name: "api", Root: filepath.Join(root, "api")}},
+		{"git subdirectory", filepath.Join(root, "api", "cmd", "server"), Repository{Name: "api", Root: filepath.Join(root, "api"), Subdirectory: "cmd/server"}},
+		{"worktree", filepath.Join(root, "api-fix", "internal"), Repository{Name: "api", Root: filepath.Join(root, "api-fix"), Subdirectory: "internal"}},
+		{"marker", filepath.Join(root, "web", "src"), Repository{Name: "web", Root: filepath.Join(root, "web"), Subdirectory: "src"}},
+		{"no repository", filepath.Join(root, "scratch"), Repository{Name: "scratch", Root: filepath.Join(root, "scratch")}},
+		{"relative path", "c:/Users/me/src", Repository{Name: "src", Root: "c:/Users/me/src"}},
+		{"empty", "", Repository{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveRepository(tt.workingDir); got != tt.want {
+				t.Errorf("ResolveRepository(%q) = %+v, want %+v", tt.workingDir, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package uilogparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SourceEnvVar is the environment variable that names the agent whose task
+// files are processed, overriding detection from the file path
+const SourceEnvVar = "COST_TRACKER_SOURCE"
+
+// Agent names recorded as the source of a ledger
+const (
+	SourceRooCode  = "roo-code"
+	SourceKiloCode = "kilo-code"
+)
+
+// Extension IDs whose global storage holds each agent's tasks
+const (
+	ClineExtensionID    = "saoudrizwan.claude-dev"
+	RooCodeExtensionID  = "rooveterinaryinc.roo-cline"
+	KiloCodeExtensionID = "kilocode.kilo-code"
+)
+
+// Source adapts the task files of Cline, one of its forks or Claude Code to
+// the Cline message model, so the rest of the pipeline only deals with Cline's
+// say/ask types and tool names
+type Source struct {
+	// Name is recorded as the ledger source
+	Name string
+	// ExtensionID identifies the agent's global storage directory, empty for
+	// agents that are not editor extensions
+	ExtensionID string
+	// FilePattern matches the base name of the agent's task files
+	FilePattern string
+	// parse reads task files that are not in the ui_messages.json format
+	parse func(filePath string) ([]UIMessage, error)
+	// says, asks and tools map the fork's say types, ask types and tool
+	// payload names onto Cline's
+	says  map[string]string
+	asks  map[string]string
+	tools map[string]string
+	// text rewrites the fork's environment details headings into Cline's
+	text *strings.Replacer
+}
+
+// rooSays are the say types Roo Code renamed from Cline's
+var rooSays = map[string]string{
+	"api_req_deleted":  "deleted_api_reqs",
+	"checkpoint_saved": "checkpoint_created",
+	"rooignore_error":  "clineignore_error",
+}
+
+// rooTools are Roo Code's file editing tools, which all edit an existing file
+var rooTools = map[string]string{
+	"appliedDiff":      "editedExistingFile",
+	"insertContent":    "editedExistingFile",
+	"searchAndReplace": "editedExistingFile",
+}
+
+// rooText renames the environment details heading holding the working
+// directory, which Roo Code calls the workspace directory
+var rooText = strings.NewReplacer("# Current Workspace Directory (", "# Current Working Directory (")
+
+// uiMessagesFile is the name of the task files of Cline and its forks
+const uiMessagesFile = "ui_messages.json"
+
+// Sources are the agents whose tasks can be processed, Cline first
+var Sources = []Source{
+	{Name: SourceCline, ExtensionID: ClineExtensionID, FilePattern: uiMessagesFile},
+	{Name: SourceRooCode, ExtensionID: RooCodeExtensionID, FilePattern: uiMessagesFile, says: rooSays, tools: rooTools, text: rooText},
+	{
+		// Kilo Code forked Roo Code and adds asks for its own billing
+		Name:        SourceKiloCode,
+		ExtensionID: KiloCodeExtensionID,
+		FilePattern: uiMessagesFile,
+		says:        rooSays,
+		asks: map[string]string{
+			"payment_required_prompt": "api_req_failed",
+			"invalid_model":           "api_req_failed",
+		},
+		tools: rooTools,
+		text:  rooText,
+	},
+	{Name: SourceClaudeCode, FilePattern: "*.jsonl", parse: ParseClaudeCodeTranscript},
+}
+
+// SourceByName returns the source with the given name
+func SourceByName(name string) (Source, error) {
+	for _, source := range Sources {
+		if source.Name == name {
+			return source, nil
+		}
+	}
+	names := make([]string, len(Sources))
+	for i, source := range Sources {
+		names[i] = source.Name
+	}
+	return Source{}, fmt.Errorf("unknown source %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// SourceForPath returns the source whose task files look like the file at
+// path and, for extensions, whose storage holds it. Cline is returned when no
+// source matches.
+func SourceForPath(path string) Source {
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+	for _, source := range Sources {
+		if matched, _ := filepath.Match(source.FilePattern, filepath.Base(path)); !matched {
+			continue
+		}
+		if source.ExtensionID == "" {
+			return source
+		}
+		for _, dir := range dirs {
+			if strings.EqualFold(dir, source.ExtensionID) {
+				return source
+			}
+		}
+	}
+	return Sources[0]
+}
+
+// SourceForTasksRoot returns the extension whose storage holds a tasks
+// directory, Cline when it names no known extension
+func SourceForTasksRoot(root string) Source {
+	for _, dir := range strings.Split(filepath.ToSlash(root), "/") {
+		for _, source := range Sources {
+			if source.ExtensionID != "" && strings.EqualFold(dir, source.ExtensionID) {
+				return source
+			}
+		}
+	}
+	return Sources[0]
+}
+
+// sourceFor returns the source named by opts, or the one detected from path
+func sourceFor(opts Options, path string) (Source, error) {
+	if opts.Source != "" {
+		return SourceByName(opts.Source)
+	}
+	return SourceForPath(path), nil
+}
+
+// ParseUIMessages reads a task file and normalizes its messages
+func (s Source) ParseUIMessages(filePath string) ([]UIMessage, error) {
+	if s.parse != nil {
+		return s.parse(filePath)
+	}
+	messages, err := ParseUIMessages(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return s.Normalize(messages), nil
+}
+
+// Normalize maps the fork's say/ask types, tool names and headings onto
+// Cline's. Messages are returned unchanged for Cline.
+func (s Source) Normalize(messages []UIMessage) []UIMessage {
+	if s.says == nil && s.asks == nil && s.tools == nil && s.text == nil {
+		return messages
+	}
+	normalized := make([]UIMessage, len(messages))
+	for i, msg := range messages {
+		if say, ok := s.says[msg.Say]; ok {
+			msg.Say = say
+		}
+		if ask, ok := s.asks[msg.Ask]; ok {
+			msg.Ask = ask
+		}
+		msg.Text = s.NormalizeText(msg.Text)
+		if (msg.Type == "ask" && msg.Ask == "tool") || (msg.Type == "say" && msg.Say == "tool") {
+			msg.Text = s.normalizeTool(msg.Text)
+		}
+		normalized[i] = msg
+	}
+	return normalized
+}
+
+// NormalizeText rewrites the fork's environment details headings in text
+func (s Source) NormalizeText(text string) string {
+	if s.text == nil {
+		return text
+	}
+	return s.text.Replace(text)
+}
+
+// normalizeTool renames the tool of a tool message payload, leaving payloads
+// it cannot decode as they are
+func (s Source) normalizeTool(text string) string {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(text), &payload); err != nil {
+		return text
+	}
+	var tool string
+	if err := json.Unmarshal(payload["tool"], &tool); err != nil {
+		return text
+	}
+	renamed, ok := s.tools[tool]
+	if !ok {
+		return text
+	}
+	payload["tool"], _ = json.Marshal(renamed)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return text
+	}
+	return string(data)
+}
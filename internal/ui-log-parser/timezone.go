@@ -0,0 +1,76 @@
+package uilogparser
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time zones timestamps can be written in
+const (
+	TimeZoneUTC   = "utc"
+	TimeZoneLocal = "local"
+	TimeZoneTask  = "task"
+)
+
+// TimeZoneEnvVar is the environment variable that selects the time zone
+const TimeZoneEnvVar = "COST_TRACKER_TIMEZONE"
+
+// currentTimePattern matches the zone Cline reports in environment details,
+// e.g. "# Current Time\n7/28/2025, 9:52:01 AM (Australia/Brisbane, UTC+10:00)"
+var currentTimePattern = regexp.MustCompile(`# Current Time(?:\\n|\n)[^()]*\(([A-Za-z][A-Za-z0-9_+-]*(?:/[A-Za-z0-9_+-]+)*), UTC([+-])(\d{1,2})(?::(\d{2}))?\)`)
+
+// TaskLocation returns the time zone reported in the first "# Current Time"
+// section of a task. The named zone is used when the system knows it, otherwise
+// a fixed zone with the reported offset.
+func TaskLocation(messages []UIMessage) (*time.Location, bool) {
+	for _, msg := range messages {
+		if !strings.Contains(msg.Text, "# Current Time") {
+			continue
+		}
+		matches := currentTimePattern.FindStringSubmatch(msg.Text)
+		if matches == nil {
+			continue
+		}
+
+		if loc, err := time.LoadLocation(matches[1]); err == nil {
+			return loc, true
+		}
+		hours, _ := strconv.Atoi(matches[3])
+		minutes, _ := strconv.Atoi(matches[4])
+		offset := hours*3600 + minutes*60
+		if matches[2] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(matches[1], offset), true
+	}
+	return nil, false
+}
+
+// ResolveLocation returns the location for a time zone setting: TimeZoneUTC,
+// TimeZoneLocal (the default), TimeZoneTask for the zone reported in the
+// task's messages, or an IANA zone name. Tasks that report no zone fall back
+// to UTC so output does not depend on the machine.
+func ResolveLocation(zone string, messages []UIMessage) (*time.Location, error) {
+	switch strings.ToLower(strings.TrimSpace(zone)) {
+	case "", TimeZoneLocal:
+		return time.Local, nil
+	case TimeZoneUTC:
+		return time.UTC, nil
+	case TimeZoneTask:
+		if loc, ok := TaskLocation(messages); ok {
+			return loc, nil
+		}
+		log.Printf("DEBUG: No time zone reported in task, using UTC")
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone: %s", zone)
+	}
+	return loc, nil
+}
@@ -0,0 +1,48 @@
+package uilogparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskLocation(t *testing.T) {
+	at := time.Date(2025, 7, 28, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		texts      []string
+		wantName   string
+		wantOffset int
+		wantOK     bool
+	}{
+		{"named zone", []string{"# Current Time\n7/28/2025, 9:52:01 AM (Australia/Brisbane, UTC+10:00)"}, "Australia/Brisbane", 10 * 3600, true},
+		{"escaped newline", []string{`<environment_details>\n# Current Time\n7/28/2025, 9:52:01 AM (Australia/Brisbane, UTC+10:00)`}, "Australia/Brisbane", 10 * 3600, true},
+		{"unknown zone keeps the offset", []string{"# Current Time\n7/28/2025, 9:52:01 AM (Mars/Olympus, UTC-3:30)"}, "Mars/Olympus", -(3*3600 + 30*60), true},
+		{"offset without minutes", []string{"# Current Time\n7/28/2025, 9:52:01 AM (Mars/Olympus, UTC+5)"}, "Mars/Olympus", 5 * 3600, true},
+		{"first reported zone wins", []string{"hello", "# Current Time\n1:00:00 PM (Mars/Olympus, UTC+1:00)", "# Current Time\n1:00:00 PM (Mars/Tharsis, UTC+2:00)"}, "Mars/Olympus", 3600, true},
+		{"skips sections without a zone", []string{"# Current Time\nsoon", "# Current Time\n1:00:00 PM (Mars/Olympus, UTC+1:00)"}, "Mars/Olympus", 3600, true},
+		{"no zone", []string{"hello"}, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantName == "Australia/Brisbane" {
+				if _, err := time.LoadLocation(tt.wantName); err != nil {
+					t.Skip("no time zone database")
+				}
+			}
+			var messages []UIMessage
+			for _, text := range tt.texts {
+				messages = append(messages, UIMessage{Type: "say", Say: "api_req_started", Text: text})
+			}
+			loc, ok := TaskLocation(messages)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if _, offset := at.In(loc).Zone(); loc.String() != tt.wantName || offset != tt.wantOffset {
+				t.Errorf("location = %s %d, want %s %d", loc, offset, tt.wantName, tt.wantOffset)
+			}
+		})
+	}
+}
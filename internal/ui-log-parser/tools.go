@@ -0,0 +1,64 @@
+package uilogparser
+
+import "encoding/json"
+
+// toolAliases maps the tool names Cline writes into "tool" message payloads
+// onto the tool names the model uses when it calls them
+var toolAliases = map[string]string{
+	"readFile":                "read_file",
+	"newFileCreated":          "write_to_file",
+	"editedExistingFile":      "replace_in_file",
+	"listFilesTopLevel":       "list_files",
+	"listFilesRecursive":      "list_files",
+	"searchFiles":             "search_files",
+	"listCodeDefinitionNames": "list_code_definition_names",
+	"webFetch":                "web_fetch",
+}
+
+// fileTools are the tools whose path refers to a file or directory in the workspace
+var fileTools = map[string]bool{
+	"read_file":       true,
+	"write_to_file":   true,
+	"replace_in_file": true,
+	"list_files":      true,
+	"search_files":    true,
+}
+
+// CanonicalToolName returns the model-facing name for a tool payload name
+func CanonicalToolName(name string) string {
+	if alias, ok := toolAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// ParseAPIRequest decodes the payload of an "api_req_started" message
+func ParseAPIRequest(msg UIMessage) (APIRequest, bool) {
+	var req APIRequest
+	if msg.Type != "say" || msg.Say != "api_req_started" {
+		return req, false
+	}
+	if err := json.Unmarshal([]byte(msg.Text), &req); err != nil {
+		return req, false
+	}
+	return req, true
+}
+
+// ParseToolCall decodes the payload of a "tool" message, which Cline writes
+// as an ask when approval is required and as a say when auto-approved
+func ParseToolCall(msg UIMessage) (ToolCall, bool) {
+	var call ToolCall
+	if !(msg.Type == "ask" && msg.Ask == "tool") && !(msg.Type == "say" && msg.Say == "tool") {
+		return call, false
+	}
+	if err := json.Unmarshal([]byte(msg.Text), &call); err != nil || call.Tool == "" {
+		return call, false
+	}
+	call.Tool = CanonicalToolName(call.Tool)
+	return call, true
+}
+
+// isFileTool reports whether a canonical tool name operates on a workspace path
+func isFileTool(tool string) bool {
+	return fileTools[tool]
+}
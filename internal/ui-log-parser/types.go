@@ -11,20 +11,139 @@ type UIMessage struct {
 
 // CostRecord represents a row in the cost tracking CSV
 type CostRecord struct {
-	RequestSummary         string
-	AskSay                 string
-	Cost                   string
-	Text                   string
-	Timestamp              string
-	ContextTokens          string
-	TotalCost              string
-	ClineAction            string
-	ToolUsed               string
-	HasImages              string
-	Phase                  string
-	ContextPercentage      string
-	SearchTermInTranscript string
-	CostNotes              string
-	TimeApprox             string
-	WorkingDirectory       string
+	RequestSummary         string `json:"request_summary"`
+	AskSay                 string `json:"ask_say"`
+	Cost                   string `json:"cost"`
+	Text                   string `json:"text"`
+	Timestamp              string `json:"timestamp"`
+	ContextTokens          string `json:"context_tokens"`
+	TotalCost              string `json:"total_cost"`
+	ClineAction            string `json:"cline_action"`
+	ToolUsed               string `json:"tool_used"`
+	HasImages              string `json:"has_images"`
+	Phase                  string `json:"phase"`
+	ContextPercentage      string `json:"context_percentage"`
+	SearchTermInTranscript string `json:"search_term_in_transcript"`
+	CostNotes              string `json:"cost_notes"`
+	TimeApprox             string `json:"time_approx"`
+	WorkingDirectory       string `json:"working_directory"`
+	Branch                 string `json:"branch,omitempty"`
+	Commit                 string `json:"commit,omitempty"`
+	Redactions             string `json:"redactions,omitempty"`
+	Currency               string `json:"currency,omitempty"`
+	ConvertedCost          string `json:"converted_cost,omitempty"`
+	ConvertedTotalCost     string `json:"converted_total_cost,omitempty"`
+}
+
+// APIRequest represents the JSON payload of an "api_req_started" message
+type APIRequest struct {
+	Request      string  `json:"request"`
+	TokensIn     int64   `json:"tokensIn"`
+	TokensOut    int64   `json:"tokensOut"`
+	CacheWrites  int64   `json:"cacheWrites"`
+	CacheReads   int64   `json:"cacheReads"`
+	Cost         float64 `json:"cost"`
+	CancelReason string  `json:"cancelReason,omitempty"`
+}
+
+// ToolCall represents the JSON payload of a "tool" ask/say message
+type ToolCall struct {
+	Tool        string `json:"tool"`
+	Path        string `json:"path,omitempty"`
+	Content     string `json:"content,omitempty"`
+	Diff        string `json:"diff,omitempty"`
+	Regex       string `json:"regex,omitempty"`
+	FilePattern string `json:"filePattern,omitempty"`
+}
+
+// FileCost represents the API cost attributed to a single file
+type FileCost struct {
+	Path      string
+	Extension string
+	Language  string
+	Requests  int
+	ToolCalls int
+	Cost      float64
+}
+
+// CostRollup represents attributed cost grouped by an arbitrary key
+type CostRollup struct {
+	Key   string
+	Files int
+	Cost  float64
+}
+
+// RedundantRead represents repeated read_file calls on the same path within a task
+type RedundantRead struct {
+	Path            string
+	Reads           int
+	RedundantReads  int
+	UnchangedReads  int
+	EstimatedTokens int64
+}
+
+// LoopSignal describes one symptom of a task that is stuck in a loop
+type LoopSignal struct {
+	Kind   string
+	Detail string
+	Count  int
+	Cost   float64
+	// Ongoing is set when the loop was still going on at the last message,
+	// and cleared for loops the task recovered from
+	Ongoing bool
+}
+
+// ContextEvent represents a point where the conversation was condensed or
+// truncated to fit the context window
+type ContextEvent struct {
+	Kind         string
+	Timestamp    int64
+	TokensBefore int64
+	TokensAfter  int64
+	Cost         float64
+}
+
+// CompletionStats splits the cost of a task at its first completion
+type CompletionStats struct {
+	Completions           int
+	Reopens               int
+	FirstCompletion       int64
+	CostToFirstCompletion float64
+	ReworkCost            float64
+}
+
+// TaskResult is the outcome of processing a task log
+type TaskResult struct {
+	OutputPath string
+	// ReportPath, MarkdownPath and HTMLPath are the reports written next to
+	// the records, empty when Options.SkipReports is set
+	ReportPath   string
+	MarkdownPath string
+	HTMLPath     string
+	Report       *TaskReport
+	Ledger       *Ledger
+	// RepoConfig holds the overrides of the task's repository, nil when it
+	// has none
+	RepoConfig *RepositoryConfig
+	// RootOutputPaths are the records written for the other roots of a
+	// multi-root workspace
+	RootOutputPaths []string
+}
+
+// ExpensiveRequest describes one API request in a task's top requests
+type ExpensiveRequest struct {
+	Timestamp   int64
+	Cost        float64
+	TokensIn    int64
+	TokensOut   int64
+	CacheWrites int64
+	CacheReads  int64
+	Summary     string
+}
+
+// ToolUsage counts the calls made to one tool and the cost attributed to them
+type ToolUsage struct {
+	Tool  string
+	Calls int
+	Cost  float64
 }
@@ -0,0 +1,193 @@
+package uilogparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// WorkspaceRootsEnvVar is the environment variable that selects which
+// workspace roots of a multi-root workspace receive a task's output
+const WorkspaceRootsEnvVar = "COST_TRACKER_WORKSPACE_ROOTS"
+
+// Workspace roots receiving a task's output
+const (
+	// WorkspaceRootsPrimary writes to the primary root only
+	WorkspaceRootsPrimary = "primary"
+	// WorkspaceRootsEach writes to every root of the workspace
+	WorkspaceRootsEach = "each"
+)
+
+// Headings of the environment details Cline sends with each request. The
+// working directory heading ends with " Files" when the file list follows.
+var (
+	workingDirectoryHeading = regexp.MustCompile(`^# Current Working Directory \((.+)\)(?: Files)?$`)
+	// workspaceRootLine is an entry of the "# Workspace Roots" list, such as
+	// "- api: /src/api (Primary)"
+	workspaceRootLine = regexp.MustCompile(`^- (?:[^:]+?: )?(.+?)(?: \(([^()]*)\))?$`)
+	windowsPath       = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+)
+
+const (
+	workspaceRootsHeading         = "# Workspace Roots"
+	workspaceConfigurationHeading = "# Workspace Configuration"
+	// placeholderWorkingDirectory appears in prompts describing the heading
+	placeholderWorkingDirectory = "/path/to/directory"
+)
+
+// workspace is the workspace described by the environment details of a
+// message
+type workspace struct {
+	// primary is the directory the agent works in
+	primary string
+	// roots are every root of the workspace, in the order described
+	roots []string
+}
+
+// requestText returns the text holding a message's environment details: the
+// request of an API request payload, or the text itself
+func requestText(text string) string {
+	if !strings.HasPrefix(text, "{") {
+		return text
+	}
+	var request APIRequest
+	if err := json.Unmarshal([]byte(text), &request); err != nil || request.Request == "" {
+		return text
+	}
+	return request.Request
+}
+
+// parseWorkspace reads the working directory heading and the workspace roots
+// list or configuration of a message. A root the list marks as primary is
+// the primary one, otherwise the working directory is.
+func parseWorkspace(text string) workspace {
+	var ws workspace
+	var marked string
+	lines := strings.Split(requestText(text), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case workingDirectoryHeading.MatchString(line):
+			if dir, ok := cleanWorkingDirectory(workingDirectoryHeading.FindStringSubmatch(line)[1]); ok {
+				ws.primary = dir
+				ws.roots = appendRoot(ws.roots, dir)
+			}
+		case line == workspaceRootsHeading:
+			for i+1 < len(lines) {
+				entry := strings.TrimSpace(lines[i+1])
+				match := workspaceRootLine.FindStringSubmatch(entry)
+				if match == nil {
+					break
+				}
+				i++
+				path, note := match[1], match[2]
+				if note != "" && !strings.EqualFold(note, "primary") && !strings.Contains(note, ":") {
+					// The parentheses were part of the path
+					path = match[1] + " (" + note + ")"
+				}
+				if dir, ok := cleanWorkingDirectory(path); ok {
+					ws.roots = appendRoot(ws.roots, dir)
+					if strings.EqualFold(note, "primary") {
+						marked = dir
+					}
+				}
+			}
+		case line == workspaceConfigurationHeading:
+			rest := strings.Join(lines[i+1:], "\n")
+			var config struct {
+				Workspaces map[string]json.RawMessage `json:"workspaces"`
+			}
+			if err := json.NewDecoder(strings.NewReader(rest)).Decode(&config); err != nil {
+				continue
+			}
+			paths := make([]string, 0, len(config.Workspaces))
+			for path := range config.Workspaces {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				if dir, ok := cleanWorkingDirectory(path); ok {
+					ws.roots = appendRoot(ws.roots, dir)
+				}
+			}
+		}
+	}
+	if marked != "" {
+		ws.primary = marked
+	}
+	if ws.primary == "" && len(ws.roots) > 0 {
+		ws.primary = ws.roots[0]
+	}
+	return ws
+}
+
+// cleanWorkingDirectory accepts the absolute directory forms agents emit:
+// POSIX paths, Windows drive paths and UNC paths, the Windows ones with
+// forward slashes as Cline writes them. Placeholders and text that is not a
+// path are rejected.
+func cleanWorkingDirectory(dir string) (string, bool) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" || dir == placeholderWorkingDirectory || strings.ContainsAny(dir, "\"\n\r\t<>|*?") {
+		return "", false
+	}
+	switch {
+	case windowsPath.MatchString(dir), strings.HasPrefix(dir, `\\`):
+		dir = strings.ReplaceAll(dir, `\`, "/")
+	case strings.HasPrefix(dir, "/"):
+	default:
+		return "", false
+	}
+	if len(dir) > 1 && !strings.HasSuffix(dir, ":/") && dir != "//" {
+		dir = strings.TrimRight(dir, "/")
+	}
+	return dir, true
+}
+
+// appendRoot appends dir to roots unless it is already there
+func appendRoot(roots []string, dir string) []string {
+	for _, root := range roots {
+		if root == dir {
+			return roots
+		}
+	}
+	return append(roots, dir)
+}
+
+// findWorkspace returns the working directories of a task: the primary one
+// of its most recent message describing its workspace, that of the first, and
+// every root of the workspace, the most recent primary first
+func findWorkspace(messages []UIMessage) (latest, first string, roots []string) {
+	var seen []string
+	for _, msg := range messages {
+		ws := parseWorkspace(msg.Text)
+		if ws.primary == "" {
+			continue
+		}
+		if first == "" {
+			first = ws.primary
+		}
+		latest = ws.primary
+		for _, root := range ws.roots {
+			seen = appendRoot(seen, root)
+		}
+	}
+	if latest == "" {
+		return "", "", nil
+	}
+	roots = []string{latest}
+	for _, root := range seen {
+		roots = appendRoot(roots, root)
+	}
+	return latest, first, roots
+}
+
+// validWorkspaceRoots checks a WorkspaceRoots setting
+func validWorkspaceRoots(mode string) error {
+	switch mode {
+	case "", WorkspaceRootsPrimary, WorkspaceRootsEach:
+		return nil
+	}
+	return fmt.Errorf("unknown workspace roots setting %q, expected %s or %s", mode, WorkspaceRootsPrimary, WorkspaceRootsEach)
+}
@@ -0,0 +1,57 @@
+package uilogparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWorkspace(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantPrimary string
+		wantRoots   []string
+	}{
+		{"posix", "# Current Working Directory (/src/api) Files\nmain.go", "/src/api", []string{"/src/api"}},
+		{"trailing slash", "# Current Working Directory (/src/api/)", "/src/api", []string{"/src/api"}},
+		{"windows backslashes", `# Current Working Directory (C:\src\api)`, "C:/src/api", []string{"C:/src/api"}},
+		{"windows slashes", "# Current Working Directory (c:/src/api)", "c:/src/api", []string{"c:/src/api"}},
+		{"windows drive root", `# Current Working Directory (D:\)`, "D:/", []string{"D:/"}},
+		{"unc", `# Current Working Directory (\\server\share\api)`, "//server/share/api", []string{"//server/share/api"}},
+		{"parentheses in path", "# Current Working Directory (/src/api (old))", "/src/api (old)", []string{"/src/api (old)"}},
+		{"placeholder", "# Current Working Directory (/path/to/directory)", "", nil},
+		{"relative", "# Current Working Directory (src/api)", "", nil},
+		{
+			"workspace roots with primary",
+			"# Workspace Roots\n- web: /src/web\n- api: /src/api (Primary)\n- docs: /src/docs (git: main)\n\n# Current Working Directory (/src/web)",
+			"/src/api", []string{"/src/web", "/src/api", "/src/docs"},
+		},
+		{
+			"workspace configuration",
+			"# Current Working Directory (/src/api)\n\n# Workspace Configuration\n{\"workspaces\": {\"/src/web\": {}, \"/src/api\": {}}}",
+			"/src/api", []string{"/src/api", "/src/web"},
+		},
+		{"api request payload", `{"request":"task\n\n# Current Working Directory (/src/api) Files","cost":0.1}`, "/src/api", []string{"/src/api"}},
+		{"no heading", "just text", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := parseWorkspace(tt.text)
+			if ws.primary != tt.wantPrimary || !reflect.DeepEqual(ws.roots, tt.wantRoots) {
+				t.Errorf("parseWorkspace() = %q %q, want %q %q", ws.primary, ws.roots, tt.wantPrimary, tt.wantRoots)
+			}
+		})
+	}
+}
+
+func TestFindWorkspace(t *testing.T) {
+	messages := []UIMessage{
+		{Type: "say", Say: "task", Text: "fix it"},
+		requestMessage("# Current Working Directory (/src/api)", 0.1),
+		requestMessage("# Workspace Roots\n- /src/api\n- /src/web (Primary)", 0.1),
+	}
+	latest, first, roots := findWorkspace(messages)
+	if latest != "/src/web" || first != "/src/api" || !reflect.DeepEqual(roots, []string{"/src/web", "/src/api"}) {
+		t.Errorf("findWorkspace() = %q, %q, %q", latest, first, roots)
+	}
+}
@@ -0,0 +1,208 @@
+package uilogparser
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats supported by NewWriter
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// FormatEnvVar is the environment variable that selects the output format
+const FormatEnvVar = "COST_TRACKER_FORMAT"
+
+// Writer writes cost records to a file in a specific format
+type Writer interface {
+	// Write writes the records to the named file, replacing its contents
+	Write(filename string, records []CostRecord) error
+	// Extension returns the file extension for this format, including the dot
+	Extension() string
+}
+
+// LedgerWriter is implemented by writers that need the typed task ledger
+// rather than the flattened cost records
+type LedgerWriter interface {
+	// WriteLedger writes the task ledger to path, replacing its contents
+	WriteLedger(path string, ledger *Ledger) error
+}
+
+// Options controls how a task log is processed and written
+type Options struct {
+	// Format selects the writer, FormatCSV when empty
+	Format string
+	// CSVProfile names the built-in column profile, ProfileStandard when empty
+	CSVProfile string
+	// CSVColumns overrides the columns of the profile when set
+	CSVColumns []string
+	// MaxTextLength overrides the profile's text length limit when positive
+	MaxTextLength int
+	// TextMode overrides the profile's text mode when set
+	TextMode string
+	// Redact masks secrets and personal data before anything is written
+	Redact bool
+	// RedactPatterns are regular expressions masked in addition to the
+	// built-in redaction rules
+	RedactPatterns []string
+	// AnonymizeSalt keys the hashes of anonymized exports
+	AnonymizeSalt string
+	// AnonymizeBucket is the granularity of anonymized task start times,
+	// DefaultAnonymizeBucket when zero
+	AnonymizeBucket time.Duration
+	// TimeZone selects the zone of record and report timestamps, see
+	// ResolveLocation
+	TimeZone string
+	// Currency is the display currency costs are converted to, USD when empty
+	Currency string
+	// FXRatesPath is the dated FX rate file used for conversion
+	FXRatesPath string
+	// OutputDir is where records and reports are written, see LogsDirectory
+	OutputDir string
+	// OutputLocation selects how the logs directory is chosen,
+	// OutputLocationRepo when empty
+	OutputLocation string
+	// FileName is the template of output file names, see OutputName
+	FileName string
+	// SkipReports leaves out the text, Markdown and HTML reports otherwise
+	// written next to each task's records
+	SkipReports bool
+	// UnchangedReadsOnly lists only re-reads of files that did not change
+	// since the previous read in the redundant reads of reports
+	UnchangedReadsOnly bool
+	// GitIgnore selects where the logs directory is ignored when it is first
+	// created in a git repository, GitIgnoreOff when empty
+	GitIgnore string
+	// WorkspaceRoots selects which roots of a multi-root workspace receive
+	// the output, WorkspaceRootsPrimary when empty
+	WorkspaceRoots string
+	// Source names the agent that wrote the task files, detected from their
+	// path when empty
+	Source string
+}
+
+// DefaultOptions returns the processing options configured in the environment
+func DefaultOptions() Options {
+	var opts Options
+	opts.ApplyEnv()
+	return opts
+}
+
+// ApplyEnv overrides options with the environment variables that are set
+func (o *Options) ApplyEnv() {
+	setString := func(field *string, name string) {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
+	}
+	setString(&o.Format, FormatEnvVar)
+	setString(&o.CSVProfile, ProfileEnvVar)
+	setString(&o.TextMode, TextModeEnvVar)
+	setString(&o.AnonymizeSalt, AnonymizeSaltEnvVar)
+	setString(&o.TimeZone, TimeZoneEnvVar)
+	setString(&o.Currency, CurrencyEnvVar)
+	setString(&o.FXRatesPath, FXRatesEnvVar)
+	setString(&o.OutputDir, OutputDirEnvVar)
+	setString(&o.OutputLocation, OutputLocationEnvVar)
+	setString(&o.FileName, FileNameEnvVar)
+	setString(&o.GitIgnore, GitIgnoreEnvVar)
+	setString(&o.WorkspaceRoots, WorkspaceRootsEnvVar)
+	setString(&o.Source, SourceEnvVar)
+
+	if columns := ParseColumnList(os.Getenv(ColumnsEnvVar)); len(columns) > 0 {
+		o.CSVColumns = columns
+	}
+	if reports, err := strconv.ParseBool(os.Getenv(ReportsEnvVar)); err == nil {
+		o.SkipReports = !reports
+	}
+	if unchangedOnly, err := strconv.ParseBool(os.Getenv(UnchangedReadsEnvVar)); err == nil {
+		o.UnchangedReadsOnly = unchangedOnly
+	}
+	if redact, err := strconv.ParseBool(os.Getenv(RedactEnvVar)); err == nil {
+		o.Redact = redact
+	}
+	if patterns := redactPatternsFromEnv(); len(patterns) > 0 {
+		o.RedactPatterns = patterns
+	}
+	if value := os.Getenv(AnonymizeBucketEnvVar); value != "" {
+		if bucket, err := time.ParseDuration(value); err == nil {
+			o.AnonymizeBucket = bucket
+		}
+	}
+	if value := os.Getenv(MaxTextLengthEnvVar); value != "" {
+		if maxLength, err := strconv.Atoi(value); err == nil {
+			o.MaxTextLength = maxLength
+		}
+	}
+}
+
+// NewWriter returns the writer for the output format selected by opts
+func NewWriter(opts Options) (Writer, error) {
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", FormatCSV:
+		profile, err := ResolveColumnProfile(opts)
+		if err != nil {
+			return nil, err
+		}
+		return CSVWriter{Profile: profile}, nil
+	case FormatJSONL, "json":
+		profile, err := ResolveColumnProfile(opts)
+		if err != nil {
+			return nil, err
+		}
+		return JSONLWriter{Profile: profile}, nil
+	case FormatBundle:
+		return BundleWriter{}, nil
+	case FormatAnonymized:
+		anonymizer, err := NewAnonymizer(opts.AnonymizeSalt, opts.AnonymizeBucket)
+		if err != nil {
+			return nil, err
+		}
+		return BundleWriter{Anonymizer: anonymizer}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", opts.Format)
+	}
+}
+
+// CSVWriter writes cost records as CSV with the columns of a profile
+type CSVWriter struct {
+	Profile ColumnProfile
+}
+
+// Write writes cost records to a CSV file, with the standard columns when no
+// profile is set
+func (w CSVWriter) Write(filename string, records []CostRecord) error {
+	if len(w.Profile.Columns) == 0 {
+		return WriteCSV(filename, records)
+	}
+	return WriteCSVWithProfile(filename, records, w.Profile)
+}
+
+// Extension returns the CSV file extension
+func (CSVWriter) Extension() string {
+	return ".csv"
+}
+
+// JSONLWriter writes cost records as JSON Lines, one object per record with
+// the columns of a profile
+type JSONLWriter struct {
+	Profile ColumnProfile
+}
+
+// Write writes cost records to a JSON Lines file, with the standard columns
+// when no profile is set
+func (w JSONLWriter) Write(filename string, records []CostRecord) error {
+	if len(w.Profile.Columns) == 0 {
+		return WriteJSONL(filename, records)
+	}
+	return WriteJSONLWithProfile(filename, records, w.Profile)
+}
+
+// Extension returns the JSON Lines file extension
+func (JSONLWriter) Extension() string {
+	return ".jsonl"
+}